@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"fmt"
+	"io/ioutil"
 	"os"
 	"strconv"
 	"strings"
@@ -14,7 +15,7 @@ import (
 
 // createAppCmd represents the app command
 var createAppCmd = &cobra.Command{
-	Use:   "app [app_name]",
+	Use:   "app [app_name | -f file]",
 	Short: "Create an app",
 	Long: `Create a new application.
 
@@ -33,8 +34,25 @@ You can optionally specify the number of containers/pods you want your app
 to run with the --scale (defaults to 1) option:
 
 	$ teresa create app my_app_name --team my_team --scale 4
+
+The app can also be created from a yaml spec, with the scale and env vars:
+
+	$ teresa create app -f my_app.yaml
+
+	name: my_app_name
+	team: my_team
+	scale: 4
+	description: the app description, for humans only
+	env:
+	  FOO: bar
+
+The spec is validated before anything is sent to the server.
 `,
 	Run: func(cmd *cobra.Command, args []string) {
+		if appFileFlag != "" {
+			createAppFromSpec(cmd, appFileFlag)
+			return
+		}
 		if len(args) == 0 {
 			Usage(cmd)
 			return
@@ -53,6 +71,80 @@ to run with the --scale (defaults to 1) option:
 	},
 }
 
+// create the app described by the spec file, failing on any invalid field
+func createAppFromSpec(cmd *cobra.Command, f string) {
+	spec, err := readAppSpec(f)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if msgs := spec.validate(); len(msgs) > 0 {
+		log.Fatalf("Invalid app spec %s:\n  %s", f, strings.Join(msgs, "\n  "))
+	}
+	team := spec.Team
+	if cmd.Flags().Changed("team") || team == "" {
+		team = teamNameFlag
+	}
+	tc := NewTeresa()
+	app, err := tc.CreateAppFromModel(tc.GetTeamID(team), spec.model())
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Infof("App created. Name: %s Replicas: %s Env vars: %d", *app.Name, formatScale(app.Scale), len(app.EnvVars))
+}
+
+var deleteAppCmd = &cobra.Command{
+	Use:   "app [app_name]",
+	Short: "Delete an app",
+	Long: `Delete an application and all its deployments.
+
+This can't be undone, so the app name must be typed again to confirm.
+Team name is only required if you are part of more than one team.
+
+eg.:
+
+	$ teresa delete app my_app_name --team my_team
+
+To save the app env vars to a file before deleting it:
+
+	$ teresa delete app my_app_name --team my_team --keep-env-export my_app.env
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		name := appNameFlag
+		if len(args) > 0 {
+			name = args[0]
+		}
+		if name == "" {
+			Usage(cmd)
+			return
+		}
+		tc := NewTeresa()
+		a := tc.GetAppInfo(teamNameFlag, name)
+
+		if envExportFlag != "" {
+			app, err := tc.GetAppDetail(a.TeamID, a.AppID)
+			if err != nil {
+				log.Fatal(err)
+			}
+			if err = ioutil.WriteFile(envExportFlag, formatDotenv(app.EnvVars), 0600); err != nil {
+				log.Fatalf("Failed to export the env vars, the app was not deleted: %s", err)
+			}
+			log.Infof("Env vars exported to %s", envExportFlag)
+		}
+
+		if !yesFlag {
+			fmt.Printf("The app %s and all its deployments will be deleted. This can't be undone.\n", name)
+			typed, err := readLine("Type the app name to confirm: ")
+			if err != nil || strings.TrimSpace(typed) != name {
+				log.Fatal("The name doesn't match, aborted and nothing was deleted")
+			}
+		}
+		if err := tc.DeleteApp(a.TeamID, a.AppID); err != nil {
+			log.Fatalf("Failed to delete the app: %s", err)
+		}
+		log.Infof("App %s deleted", name)
+	},
+}
+
 var getAppCmd = &cobra.Command{
 	Use:   "app",
 	Short: "Get app info",
@@ -245,6 +337,13 @@ func init() {
 	createCmd.AddCommand(createAppCmd)
 	createAppCmd.Flags().StringVar(&teamNameFlag, "team", "", "team name")
 	createAppCmd.Flags().IntVar(&appScaleFlag, "scale", 1, "replicas")
+	createAppCmd.Flags().StringVarP(&appFileFlag, "file", "f", "", "yaml spec of the app, - for stdin")
+
+	deleteCmd.AddCommand(deleteAppCmd)
+	deleteAppCmd.Flags().StringVar(&appNameFlag, "app", "", "app name")
+	deleteAppCmd.Flags().StringVar(&teamNameFlag, "team", "", "team name")
+	deleteAppCmd.Flags().StringVar(&envExportFlag, "keep-env-export", "", "file to save the app env vars to, before deleting")
+	deleteAppCmd.Flags().BoolVarP(&yesFlag, "yes", "y", false, "don't ask for confirmation")
 
 	getCmd.AddCommand(getAppCmd)
 	getAppCmd.Flags().StringVar(&appNameFlag, "app", "", "app name")
@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sort"
+
+	"github.com/go-openapi/errors"
+	"github.com/go-openapi/strfmt"
+	"github.com/luizalabs/teresa-api/models"
+	"gopkg.in/yaml.v2"
+)
+
+var envVarKeyRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// declarative description of an app, eg.:
+//
+//	name: webapi
+//	team: site
+//	scale: 2
+//	description: checkout api
+//	env:
+//	  FOO: bar
+type appSpec struct {
+	Name  string            `yaml:"name"`
+	Team  string            `yaml:"team,omitempty"`
+	Scale *int64            `yaml:"scale,omitempty"`
+	Env   map[string]string `yaml:"env,omitempty"`
+	// free text for whoever reads the spec, the api doesn't store it
+	Description string `yaml:"description,omitempty"`
+}
+
+// read an app spec from a yaml file, or from stdin if the file is "-"
+func readAppSpec(f string) (*appSpec, error) {
+	var y []byte
+	var err error
+	if f == "-" {
+		y, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		y, err = ioutil.ReadFile(f)
+	}
+	if err != nil {
+		return nil, err
+	}
+	s := appSpec{}
+	if err = yaml.Unmarshal(y, &s); err != nil {
+		return nil, fmt.Errorf("Invalid app spec %s: %s", f, err)
+	}
+	return &s, nil
+}
+
+// replicas of the app, defaults to 1
+func (s *appSpec) scale() int64 {
+	if s.Scale == nil {
+		return 1
+	}
+	return *s.Scale
+}
+
+// the env vars, sorted by key
+func (s *appSpec) envVars() []*models.EnvVar {
+	keys := make([]string, 0, len(s.Env))
+	for k := range s.Env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	l := make([]*models.EnvVar, len(keys))
+	for i := range keys {
+		k, v := keys[i], s.Env[keys[i]]
+		l[i] = &models.EnvVar{Key: &k, Value: &v}
+	}
+	return l
+}
+
+func (s *appSpec) model() *models.App {
+	name, scale := s.Name, s.scale()
+	return &models.App{Name: &name, Scale: &scale, EnvVars: s.envVars()}
+}
+
+// validate the spec with the api models validators, returning one
+// message per invalid field
+func (s *appSpec) validate() (msgs []string) {
+	if s.Name == "" {
+		msgs = append(msgs, "name: is required")
+	}
+	// an explicit 0 isn't taken as the default
+	if s.scale() < 1 {
+		msgs = append(msgs, "scale: at least one replica is required")
+	}
+	app := s.model()
+	for _, e := range app.EnvVars {
+		p := fmt.Sprintf("env %s: ", *e.Key)
+		if !envVarKeyRegexp.MatchString(*e.Key) {
+			msgs = append(msgs, p+"invalid name, use only letters, digits and _")
+		}
+		msgs = append(msgs, validationMessages(p, e.Validate(strfmt.Default))...)
+	}
+	// the env vars were already validated one by one, with their names
+	app.EnvVars = nil
+	msgs = append(msgs, validationMessages("", app.Validate(strfmt.Default))...)
+	return
+}
+
+// flatten the swagger validation errors into messages
+func validationMessages(prefix string, err error) []string {
+	if err == nil {
+		return nil
+	}
+	if c, ok := err.(*errors.CompositeError); ok {
+		var msgs []string
+		for _, e := range c.Errors {
+			msgs = append(msgs, validationMessages(prefix, e)...)
+		}
+		return msgs
+	}
+	return []string{prefix + err.Error()}
+}
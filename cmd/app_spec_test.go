@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-openapi/swag"
+	"gopkg.in/yaml.v2"
+)
+
+func TestAppSpecValidate(t *testing.T) {
+	s := appSpec{Name: "webapi", Env: map[string]string{"FOO": "bar"}}
+	if msgs := s.validate(); len(msgs) > 0 {
+		t.Errorf("expected a valid spec, got %v", msgs)
+	}
+	if *s.model().Scale != 1 {
+		t.Errorf("expected the scale to default to 1, got %d", *s.model().Scale)
+	}
+
+	zero := appSpec{}
+	if err := yaml.Unmarshal([]byte("name: webapi\nscale: 0\n"), &zero); err != nil {
+		t.Fatal(err)
+	}
+	if msgs := zero.validate(); len(msgs) != 1 || !strings.HasPrefix(msgs[0], "scale") {
+		t.Errorf("expected an explicit scale 0 to be rejected, got %v", msgs)
+	}
+
+	s = appSpec{Scale: swag.Int64(-1), Env: map[string]string{"1FOO": "bar"}}
+	msgs := s.validate()
+	for _, field := range []string{"name", "scale", "env 1FOO"} {
+		found := false
+		for _, m := range msgs {
+			if strings.HasPrefix(m, field) {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("expected a message for the field %s, got %v", field, msgs)
+		}
+	}
+}
@@ -0,0 +1,342 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/luizalabs/teresa-api/models"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+var applyCmd = &cobra.Command{
+	Use:   "apply -f file",
+	Short: "Converge apps, scale and env vars to a yaml spec",
+	Long: `Create and update apps to match a yaml spec of the teams apps.
+
+For every app on the spec, the app is created if it doesn't exist yet, and
+its scale and env vars are updated to match the spec. Env vars not on the
+spec are removed. An app without scale keeps its current scale (new apps
+get 1 replica), and an app without env keeps its env vars, use "env: {}"
+to remove them all. Apps not on the spec are left untouched. The team
+email, url and members are only used by teresa import, and rejected here.
+
+The changes show the names of the env vars, but not their values.
+
+eg.:
+
+	$ teresa apply -f platform.yaml
+
+	teams:
+	- name: site
+	  apps:
+	  - name: webapi
+	    scale: 2
+	    env:
+	      FOO: bar
+	  - name: worker
+
+To only show what would be changed:
+
+	$ teresa apply -f platform.yaml --dry-run
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if appFileFlag == "" {
+			Usage(cmd)
+			return
+		}
+		spec, err := readApplySpec(appFileFlag)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if msgs := append(spec.validate(), spec.importOnlyFields()...); len(msgs) > 0 {
+			log.Fatalf("Invalid spec %s:\n  %s", appFileFlag, strings.Join(msgs, "\n  "))
+		}
+		tc := NewTeresa()
+		plan, err := planApply(tc, spec)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if len(plan) == 0 {
+			log.Info("Nothing to change")
+			return
+		}
+		printPlan(plan)
+		if dryRunFlag {
+			return
+		}
+		if err := runPlan(tc, plan); err != nil {
+			log.Fatal(err)
+		}
+		log.Info("Apply finished")
+	},
+}
+
+type teamSpec struct {
+	Name string `yaml:"name"`
+	// email, url and members are only used by import
+	Email   string    `yaml:"email,omitempty"`
+	URL     string    `yaml:"url,omitempty"`
+	Members []string  `yaml:"members,omitempty"`
+	Apps    []appSpec `yaml:"apps,omitempty"`
+}
+
+// spec of the apps of many teams, as read by apply, import and written by export
+type applySpec struct {
+	Teams []teamSpec `yaml:"teams"`
+}
+
+func readApplySpec(f string) (*applySpec, error) {
+	var y []byte
+	var err error
+	if f == "-" {
+		y, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		y, err = ioutil.ReadFile(f)
+	}
+	if err != nil {
+		return nil, err
+	}
+	s := applySpec{}
+	if err = yaml.Unmarshal(y, &s); err != nil {
+		return nil, fmt.Errorf("Invalid spec %s: %s", f, err)
+	}
+	return &s, nil
+}
+
+func (s *applySpec) validate() (msgs []string) {
+	teams := make(map[string]bool)
+	for i, t := range s.Teams {
+		if t.Name == "" {
+			msgs = append(msgs, fmt.Sprintf("teams[%d]: name is required", i))
+			continue
+		}
+		if teams[t.Name] {
+			msgs = append(msgs, fmt.Sprintf("team %s: declared more than once", t.Name))
+		}
+		teams[t.Name] = true
+		apps := make(map[string]bool)
+		for j, a := range t.Apps {
+			p := fmt.Sprintf("team %s, app %s: ", t.Name, a.Name)
+			if a.Name == "" {
+				p = fmt.Sprintf("team %s, apps[%d]: ", t.Name, j)
+			} else if apps[a.Name] {
+				msgs = append(msgs, p+"declared more than once")
+			}
+			apps[a.Name] = true
+			if a.Team != "" && a.Team != t.Name {
+				msgs = append(msgs, p+"belongs to another team")
+			}
+			for _, m := range a.validate() {
+				msgs = append(msgs, p+m)
+			}
+		}
+	}
+	return
+}
+
+// apply only converges the apps, so the team fields used by import are
+// rejected instead of silently ignored
+func (s *applySpec) importOnlyFields() (msgs []string) {
+	for _, t := range s.Teams {
+		if t.Email != "" || t.URL != "" || len(t.Members) > 0 {
+			msgs = append(msgs, fmt.Sprintf("team %s: email, url and members are only used by teresa import, use set team and add team-user instead", t.Name))
+		}
+	}
+	return
+}
+
+const (
+	actionCreateTeam = "create team"
+	actionUpdateTeam = "update team"
+	actionAddMember  = "add member"
+	actionCreateApp  = "create"
+	actionScaleApp   = "scale"
+	actionUpdateEnv  = "env"
+)
+
+// a change needed to converge an app (or team, on import) to its spec
+type applyAction struct {
+	kind string
+	team string
+	// zero when the team is created by an earlier action
+	teamID int64
+	// the team spec, for actionCreateTeam
+	teamSpec *teamSpec
+	// the current and updated team, for actionUpdateTeam
+	current, updated *models.Team
+	// the user email, for actionAddMember
+	member string
+	// the current app, nil when it doesn't exist yet
+	app  *models.App
+	spec *appSpec
+	// env vars changes, for actionUpdateEnv
+	set   []*models.EnvVar
+	unset []string
+}
+
+func (a applyAction) String() string {
+	switch a.kind {
+	case actionCreateTeam:
+		return fmt.Sprintf("+ create team %s", a.team)
+	case actionUpdateTeam:
+		var changes []string
+		if a.current.Email != a.updated.Email {
+			changes = append(changes, fmt.Sprintf("    ~ email: %s -> %s", a.current.Email, a.updated.Email))
+		}
+		if a.current.URL != a.updated.URL {
+			changes = append(changes, fmt.Sprintf("    ~ url: %s -> %s", a.current.URL, a.updated.URL))
+		}
+		return fmt.Sprintf("~ update team %s\n%s", a.team, strings.Join(changes, "\n"))
+	case actionAddMember:
+		return fmt.Sprintf("+ add %s to team %s", a.member, a.team)
+	case actionCreateApp:
+		return fmt.Sprintf("+ create app %s/%s (scale: %d, env vars: %d)", a.team, a.spec.Name, a.spec.scale(), len(a.spec.Env))
+	case actionScaleApp:
+		var from int64
+		if a.app.Scale != nil {
+			from = *a.app.Scale
+		}
+		return fmt.Sprintf("~ scale app %s/%s from %d to %d", a.team, a.spec.Name, from, a.spec.scale())
+	}
+	// only the names, the values are usually secrets
+	current := make(map[string]bool, len(a.app.EnvVars))
+	for _, e := range a.app.EnvVars {
+		current[*e.Key] = true
+	}
+	var changes []string
+	for _, e := range a.set {
+		if current[*e.Key] {
+			changes = append(changes, fmt.Sprintf("    ~ %s (changed)", *e.Key))
+		} else {
+			changes = append(changes, fmt.Sprintf("    + %s", *e.Key))
+		}
+	}
+	for _, k := range a.unset {
+		changes = append(changes, fmt.Sprintf("    - %s", k))
+	}
+	return fmt.Sprintf("~ update env vars of app %s/%s\n%s", a.team, a.spec.Name, strings.Join(changes, "\n"))
+}
+
+func (a applyAction) run(tc TeresaClient) error {
+	switch a.kind {
+	case actionCreateTeam:
+		_, err := tc.CreateTeam(a.team, a.teamSpec.Email, a.teamSpec.URL)
+		return err
+	case actionUpdateTeam:
+		_, err := tc.UpdateTeam(a.updated)
+		return err
+	case actionAddMember:
+		if err := tc.AddUserToTeam(a.team, a.member); err != nil {
+			return fmt.Errorf("%s (the user must exist on the cluster)", teamUserErrorMessage(err))
+		}
+		return nil
+	}
+	if a.teamID == 0 {
+		t, err := tc.GetTeamByName(a.team)
+		if err != nil {
+			return err
+		}
+		a.teamID = t.ID
+	}
+	switch a.kind {
+	case actionCreateApp:
+		_, err := tc.CreateAppFromModel(a.teamID, a.spec.model())
+		return err
+	case actionScaleApp:
+		scale := a.spec.scale()
+		app := &models.App{Name: a.app.Name, Scale: &scale, EnvVars: a.app.EnvVars}
+		_, err := tc.UpdateApp(a.teamID, a.app.ID, app)
+		return err
+	}
+	return tc.PartialUpdateApp(a.teamID, a.app.ID, envVarsPatch(a.set, a.unset))
+}
+
+// compute the actions to converge the apps to the spec
+func planApply(tc TeresaClient, spec *applySpec) ([]applyAction, error) {
+	var plan []applyAction
+	for i := range spec.Teams {
+		team, err := tc.GetTeamByName(spec.Teams[i].Name)
+		if err != nil {
+			return nil, err
+		}
+		p, err := planTeamApps(tc, &spec.Teams[i], team.ID)
+		if err != nil {
+			return nil, err
+		}
+		plan = append(plan, p...)
+	}
+	return plan, nil
+}
+
+// compute the actions to converge the apps of a team to its spec
+func planTeamApps(tc TeresaClient, ts *teamSpec, teamID int64) ([]applyAction, error) {
+	current, err := tc.GetApps(teamID)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to retrieve the apps of team %s: %s", ts.Name, err)
+	}
+	ids := make(map[string]int64, len(current))
+	for _, a := range current {
+		ids[*a.Name] = a.ID
+	}
+	var plan []applyAction
+	for i := range ts.Apps {
+		var app *models.App
+		if id, ok := ids[ts.Apps[i].Name]; ok {
+			if app, err = tc.GetAppDetail(teamID, id); err != nil {
+				return nil, fmt.Errorf("Failed to retrieve the app %s/%s: %s", ts.Name, ts.Apps[i].Name, err)
+			}
+		}
+		plan = append(plan, planApp(ts.Name, teamID, app, &ts.Apps[i])...)
+	}
+	return plan, nil
+}
+
+// compute the actions to converge a single app to its spec
+func planApp(team string, teamID int64, app *models.App, spec *appSpec) []applyAction {
+	if app == nil {
+		return []applyAction{{kind: actionCreateApp, team: team, teamID: teamID, spec: spec}}
+	}
+	// the scale and env vars left out of the spec are kept as they are,
+	// while an empty env (env: {}) removes them all
+	var plan []applyAction
+	if spec.Scale != nil && (app.Scale == nil || *app.Scale != *spec.Scale) {
+		plan = append(plan, applyAction{kind: actionScaleApp, team: team, teamID: teamID, app: app, spec: spec})
+	}
+	if spec.Env == nil {
+		return plan
+	}
+	set, unset := diffEnvVars(app.EnvVars, spec.Env)
+	if len(set) > 0 || len(unset) > 0 {
+		plan = append(plan, applyAction{kind: actionUpdateEnv, team: team, teamID: teamID, app: app, spec: spec, set: set, unset: unset})
+	}
+	return plan
+}
+
+func printPlan(plan []applyAction) {
+	fmt.Println("\nPlan:")
+	for _, a := range plan {
+		fmt.Println(a)
+	}
+	fmt.Println()
+}
+
+// run the actions in order, stopping on the first failure
+func runPlan(tc TeresaClient, plan []applyAction) error {
+	for i, a := range plan {
+		summary := strings.SplitN(a.String(), "\n", 2)[0]
+		log.Info(summary)
+		if err := a.run(tc); err != nil {
+			return fmt.Errorf("Failed to %s (%d of %d changes done): %s", strings.TrimLeft(summary, "+~ "), i, len(plan), err)
+		}
+	}
+	return nil
+}
+
+func init() {
+	applyCmd.Flags().StringVarP(&appFileFlag, "file", "f", "", "yaml spec of the teams apps, - for stdin [required]")
+	applyCmd.Flags().BoolVar(&dryRunFlag, "dry-run", false, "only show the changes")
+	RootCmd.AddCommand(applyCmd)
+}
@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-openapi/swag"
+	"github.com/luizalabs/teresa-api/models"
+	"gopkg.in/yaml.v2"
+)
+
+func newTestApp(name string, scale int64, env map[string]string) *models.App {
+	s := appSpec{Name: name, Scale: &scale, Env: env}
+	app := s.model()
+	app.ID = 1
+	return app
+}
+
+func TestPlanApp(t *testing.T) {
+	spec := &appSpec{Name: "webapi", Scale: swag.Int64(2), Env: map[string]string{"FOO": "bar", "BAZ": "new"}}
+
+	plan := planApp("site", 1, nil, spec)
+	if len(plan) != 1 || plan[0].kind != actionCreateApp {
+		t.Fatalf("expected a create action for a missing app, got %v", plan)
+	}
+
+	app := newTestApp("webapi", 2, map[string]string{"FOO": "bar", "BAZ": "new"})
+	if plan = planApp("site", 1, app, spec); len(plan) != 0 {
+		t.Fatalf("expected no actions for an app matching the spec, got %v", plan)
+	}
+
+	app = newTestApp("webapi", 1, map[string]string{"FOO": "bar", "BAZ": "old", "OLD": "x"})
+	plan = planApp("site", 1, app, spec)
+	if len(plan) != 2 || plan[0].kind != actionScaleApp || plan[1].kind != actionUpdateEnv {
+		t.Fatalf("expected scale and env actions, got %v", plan)
+	}
+	env := plan[1]
+	if len(env.set) != 1 || *env.set[0].Key != "BAZ" || *env.set[0].Value != "new" {
+		t.Errorf("expected BAZ to be updated, got %v", env.set)
+	}
+	if len(env.unset) != 1 || env.unset[0] != "OLD" {
+		t.Errorf("expected OLD to be removed, got %v", env.unset)
+	}
+}
+
+func TestPlanAppOnlyName(t *testing.T) {
+	app := newTestApp("worker", 3, map[string]string{"FOO": "bar"})
+	if plan := planApp("site", 1, app, &appSpec{Name: "worker"}); len(plan) != 0 {
+		t.Errorf("expected the scale and env vars left out of the spec to be kept, got %v", plan)
+	}
+
+	s := applySpec{}
+	if err := yaml.Unmarshal([]byte("teams:\n- name: site\n  apps:\n  - name: worker\n    env: {}\n"), &s); err != nil {
+		t.Fatal(err)
+	}
+	plan := planApp("site", 1, app, &s.Teams[0].Apps[0])
+	if len(plan) != 1 || plan[0].kind != actionUpdateEnv || len(plan[0].unset) != 1 {
+		t.Errorf("expected an empty env to remove the env vars, got %v", plan)
+	}
+}
+
+func TestPlanTeamAppsPages(t *testing.T) {
+	api := &fakeAPI{
+		responses: map[string]string{"/v1/teams/1/apps/51": `{"id": 51, "name": "app50", "scale": 1, "envVars": [{"key": "TOKEN", "value": "old-secret"}]}`},
+		lists:     map[string][]string{"/v1/teams/1/apps": newTestItems("app", pageSize+1)},
+	}
+	tc, ts := newTestClient(t, api)
+	defer ts.Close()
+
+	l, err := tc.GetApps(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(l) != pageSize+1 {
+		t.Fatalf("expected %d apps from 2 pages, got %d", pageSize+1, len(l))
+	}
+
+	spec := &teamSpec{Name: "site", Apps: []appSpec{{Name: "app50", Env: map[string]string{"TOKEN": "new-secret", "FOO": "bar"}}}}
+	plan, err := planTeamApps(tc, spec, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan) != 1 || plan[0].kind != actionUpdateEnv {
+		t.Fatalf("expected only the env vars of the app from the second page updated, got %v", plan)
+	}
+	s := plan[0].String()
+	if strings.Contains(s, "secret") || !strings.Contains(s, "~ TOKEN") || !strings.Contains(s, "+ FOO") {
+		t.Errorf("expected the env var names without the values, got:\n%s", s)
+	}
+}
+
+func TestApplySpecImportOnlyFields(t *testing.T) {
+	s := applySpec{Teams: []teamSpec{{Name: "site"}, {Name: "checkout", Members: []string{"john@luizalabs.com"}}}}
+	if msgs := s.importOnlyFields(); len(msgs) != 1 || !strings.HasPrefix(msgs[0], "team checkout") {
+		t.Errorf("expected the members of checkout to be rejected, got %v", msgs)
+	}
+}
@@ -18,8 +18,13 @@ file-path and name.
 Logout and in again to reload the completion scripts,
 or just source them in directly:
 
-    $ . /etc/bash_completion`,
+    $ . /etc/bash_completion
+
+To write the completion script to the standard output, or for other
+shells, see: teresa completion --help`,
 	Run: func(cmd *cobra.Command, args []string) {
+		markCompletionFlags(cmd.Root())
+		cmd.Root().BashCompletionFunction = bashCompletionFunction(cmd.Root())
 		if err := cmd.Root().GenBashCompletionFile(autocompleteTarget); err != nil {
 			log.Warning("If you runned as non-root without a --completionfile='', we may have had permission issues.")
 			log.Fatal(err)
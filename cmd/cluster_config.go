@@ -3,7 +3,12 @@ package cmd
 import (
 	"errors"
 	"fmt"
+	"os"
+	"sort"
 
+	"github.com/go-openapi/runtime"
+	"github.com/go-openapi/strfmt"
+	"github.com/olekukonko/tablewriter"
 	"github.com/spf13/cobra"
 )
 
@@ -15,6 +20,25 @@ var setClusterCmd = &cobra.Command{
 eg.:
 
 	$ teresa config set-cluster aws_staging --server https://staging.mydomain.com
+
+To trust a private CA or authenticate with a client certificate:
+
+	$ teresa config set-cluster aws_staging --server https://staging.mydomain.com \
+		--ca-file ca.pem --client-cert cert.pem --client-key key.pem
+
+To reach the cluster through a proxy (http, https or socks5, like an ssh tunnel):
+
+	$ teresa config set-cluster aws_staging --server https://staging.mydomain.com \
+		--proxy socks5://localhost:1080
+
+Without a proxy, the HTTPS_PROXY, HTTP_PROXY and NO_PROXY env vars are respected.
+
+For an api mounted under a path, like behind a gateway, include it in the
+server url. The api version (v1 by default) is appended to it:
+
+	$ teresa config set-cluster aws_staging --server https://gw.mydomain.com/teresa --api-version v1
+
+The server is checked to be reachable before saving, unless --skip-check is given.
 	`,
 	Run: func(cmd *cobra.Command, args []string) {
 		if len(args) == 0 {
@@ -25,7 +49,24 @@ eg.:
 		if serverFlag == "" {
 			Fatalf(cmd, "Server not provided")
 		}
-		if err := setCluster(name, serverFlag, currentFlag, cfgFile); err != nil {
+		cluster := clusterConfig{
+			Server:                serverFlag,
+			CAFile:                caFileFlag,
+			ClientCert:            clientCertFlag,
+			ClientKey:             clientKeyFlag,
+			InsecureSkipTLSVerify: insecureFlag,
+			ProxyURL:              proxyFlag,
+			APIVersion:            apiVersionFlag,
+		}
+		if cluster.InsecureSkipTLSVerify {
+			log.Warn("The server certificate won't be verified, use it only for testing")
+		}
+		if !skipCheckFlag {
+			if err := checkCluster(cluster); err != nil {
+				Fatalf(cmd, "%s\nUse --skip-check to save it anyway", err)
+			}
+		}
+		if err := setCluster(name, cluster, currentFlag, cfgFile); err != nil {
 			Fatalf(cmd, "%s", err)
 		}
 	},
@@ -46,64 +87,254 @@ eg.:
 			return
 		}
 		name := args[0]
-		setCurrentCluster(name, cfgFile)
+		if err := setCurrentCluster(name, cfgFile); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+var deleteClusterCmd = &cobra.Command{
+	Use:   "delete-cluster name",
+	Short: "removes a cluster entry from the config file",
+	Long: `Remove a cluster entry, including its auth token.
+
+If the cluster is the current one, no cluster will be in use afterwards.
+
+eg.:
+
+	$ teresa config delete-cluster aws_staging
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) == 0 {
+			Usage(cmd)
+			return
+		}
+		if err := deleteCluster(args[0], cfgFile); err != nil {
+			log.Fatal(err)
+		}
+		log.Infof("Cluster %s deleted", args[0])
+	},
+}
+
+var renameClusterCmd = &cobra.Command{
+	Use:   "rename-cluster old_name new_name",
+	Short: "renames a cluster entry in the config file",
+	Long: `Rename a cluster entry, keeping its server and auth token.
+
+eg.:
+
+	$ teresa config rename-cluster aws_staging staging
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 2 {
+			Usage(cmd)
+			return
+		}
+		if err := renameCluster(args[0], args[1], cfgFile); err != nil {
+			log.Fatal(err)
+		}
+		log.Infof("Cluster %s renamed to %s", args[0], args[1])
+	},
+}
+
+var getClustersCmd = &cobra.Command{
+	Use:   "get-clusters",
+	Short: "lists the clusters in the config file",
+	Long: `List all the configured clusters.
+
+The current cluster is marked with an "*".
+
+eg.:
+
+	$ teresa config get-clusters
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		c, err := readOrCreateConfigFile(cfgFile)
+		if err != nil {
+			log.Fatalf("Failed to read config file: %s", err)
+		}
+		names := make([]string, 0, len(c.Clusters))
+		for n := range c.Clusters {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{"CURRENT", "NAME", "SERVER", "LOGGED IN"})
+		table.SetAlignment(tablewriter.ALIGN_LEFT)
+		table.SetAutoWrapText(false)
+		for _, n := range names {
+			current := ""
+			if n == c.CurrentCluster {
+				current = "*"
+			}
+			table.Append([]string{current, n, c.Clusters[n].Server, yesNo(c.Clusters[n].Token != "")})
+		}
+		table.Render()
+	},
+}
+
+var currentClusterCmd = &cobra.Command{
+	Use:   "current-cluster",
+	Short: "shows the cluster in use",
+	Long: `Show the name of the cluster every action will be sent to.
+
+eg.:
+
+	$ teresa config current-cluster
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		n, err := getCurrentClusterName()
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println(n)
 	},
 }
 
+// check the teresa api answers on the cluster url: the current user
+// endpoint must exist, even if the request isn't authorized
+func checkCluster(cluster clusterConfig) error {
+	tc, err := newTeresaClient(cluster)
+	if err != nil {
+		return err
+	}
+	params := func(r runtime.ClientRequest, reg strfmt.Registry) error { return nil }
+	err = tc.submit("getCurrentUser", "GET", "/users/me", params, nil)
+	if err == nil {
+		return nil
+	}
+	e, ok := err.(*apiError)
+	if !ok {
+		return fmt.Errorf("Failed to reach the server: %s", err)
+	}
+	switch e.Code() {
+	case 401, 403:
+		return nil
+	case 404:
+		return newSysError(fmt.Sprintf("Teresa api not found at %s, check the server path and the api version (%s)", cluster.Server, cluster.apiVersion()))
+	}
+	return fmt.Errorf("Unexpected answer from the server: %s", err)
+}
+
 // add a new server to the config file
-func setCluster(name string, server string, current bool, f string) error {
-	if name == "" || server == "" || f == "" {
+func setCluster(name string, cluster clusterConfig, current bool, f string) error {
+	if name == "" || cluster.Server == "" || f == "" {
 		return errors.New("Name, server and filename must be provided")
 	}
 
-	// try and parse the server url upfront
-	if _, err := ParseServerURL(server); err != nil {
+	// try and parse the server url and load the certs upfront
+	if _, err := ParseServerURL(cluster.Server); err != nil {
+		return err
+	}
+	if err := absCertPaths(&cluster); err != nil {
+		return err
+	}
+	if _, err := newHTTPTransport(cluster); err != nil {
 		return err
 	}
 
-	c, err := readOrCreateConfigFile(f)
+	err := updateConfigFile(f, func(c *configFile) error {
+		c.Clusters[name] = cluster
+		// check and set this new cluster as the current one (default cluster)
+		if current {
+			c.CurrentCluster = name
+		}
+		return nil
+	})
 	if err != nil {
 		return err
 	}
+	clearClusterCache(f, name)
+	return nil
+}
 
-	c.Clusters[name] = clusterConfig{Server: server}
-	// check and set this new cluster as the current one (default cluster)
-	if current {
-		c.CurrentCluster = name
+func setCurrentCluster(name string, f string) error {
+	if name == "" || f == "" {
+		return errors.New("Name and filename must be provided")
 	}
-	if err := writeConfigFile(f, c); err != nil {
+	err := updateConfigFile(f, func(c *configFile) error {
+		if len(c.Clusters) == 0 {
+			return newSysError("There is no cluster configured yet.")
+		}
+		if _, e := c.Clusters[name]; !e {
+			return newSysError(fmt.Sprintf(`Cluster "%s" not configured yet`, name))
+		}
+		// set the cluster as the current one
+		c.CurrentCluster = name
+		return nil
+	})
+	if err != nil {
 		return err
 	}
+	log.WithField("clusterName", name).Debug("New cluster set as current")
 	return nil
 }
 
-func setCurrentCluster(name string, f string) error {
+// remove a cluster from the config file
+func deleteCluster(name string, f string) error {
 	if name == "" || f == "" {
 		return errors.New("Name and filename must be provided")
 	}
-	c, err := readOrCreateConfigFile(f)
+	err := updateConfigFile(f, func(c *configFile) error {
+		if _, e := c.Clusters[name]; !e {
+			return newSysError(fmt.Sprintf(`Cluster "%s" not configured`, name))
+		}
+		delete(c.Clusters, name)
+		if c.CurrentCluster == name {
+			c.CurrentCluster = ""
+		}
+		return nil
+	})
 	if err != nil {
 		return err
 	}
-	if len(c.Clusters) == 0 {
-		return newSysError("There is no cluster configured yet.")
-	}
-	if _, e := c.Clusters[name]; !e {
-		return newSysError(fmt.Sprintf(`Cluster "%s" not configured yet`, name))
+	clearClusterCache(f, name)
+	return nil
+}
+
+// rename a cluster in the config file, keeping the current one pointing to it
+func renameCluster(oldName, newName string, f string) error {
+	if oldName == "" || newName == "" || f == "" {
+		return errors.New("Old name, new name and filename must be provided")
 	}
-	// set the cluster as the current one
-	c.CurrentCluster = name
-	// write the config file
-	if err := writeConfigFile(f, c); err != nil {
+	err := updateConfigFile(f, func(c *configFile) error {
+		cluster, e := c.Clusters[oldName]
+		if !e {
+			return newSysError(fmt.Sprintf(`Cluster "%s" not configured`, oldName))
+		}
+		if _, e := c.Clusters[newName]; e {
+			return newSysError(fmt.Sprintf(`Cluster "%s" already exists`, newName))
+		}
+		delete(c.Clusters, oldName)
+		c.Clusters[newName] = cluster
+		if c.CurrentCluster == oldName {
+			c.CurrentCluster = newName
+		}
+		return nil
+	})
+	if err != nil {
 		return err
 	}
-	log.WithField("clusterName", name).Debug("New cluster set as current")
+	clearClusterCache(f, oldName)
+	clearClusterCache(f, newName)
 	return nil
 }
 
 func init() {
 	setClusterCmd.Flags().StringVarP(&serverFlag, "server", "s", "", "URI of the server")
 	setClusterCmd.Flags().BoolVar(&currentFlag, "current", false, "Set this server to future use")
+	setClusterCmd.Flags().StringVar(&caFileFlag, "ca-file", "", "PEM file with the CA certificates to trust")
+	setClusterCmd.Flags().StringVar(&clientCertFlag, "client-cert", "", "PEM file with the client certificate")
+	setClusterCmd.Flags().StringVar(&clientKeyFlag, "client-key", "", "PEM file with the client certificate key")
+	setClusterCmd.Flags().BoolVar(&insecureFlag, "insecure-skip-tls-verify", false, "Don't verify the server certificate (insecure)")
+	setClusterCmd.Flags().StringVar(&proxyFlag, "proxy", "", "URL of the http, https or socks5 proxy to reach the server")
+	setClusterCmd.Flags().StringVar(&apiVersionFlag, "api-version", defaultAPIVersion, "Version of the api")
+	setClusterCmd.Flags().BoolVar(&skipCheckFlag, "skip-check", false, "Don't check the server is reachable")
 	configCmd.AddCommand(setClusterCmd)
 	configCmd.AddCommand(useClusterCmd)
+	configCmd.AddCommand(deleteClusterCmd)
+	configCmd.AddCommand(renameClusterCmd)
+	configCmd.AddCommand(getClustersCmd)
+	configCmd.AddCommand(currentClusterCmd)
 }
@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func newTempConfigFile(t *testing.T) (string, func()) {
+	d, err := ioutil.TempDir("", "teresa")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return filepath.Join(d, "config.yaml"), func() { os.RemoveAll(d) }
+}
+
+func TestDeleteCluster(t *testing.T) {
+	f, cleanup := newTempConfigFile(t)
+	defer cleanup()
+
+	if err := setCluster("staging", clusterConfig{Server: "http://staging.mydomain.com"}, true, f); err != nil {
+		t.Fatal(err)
+	}
+	if err := setCluster("prod", clusterConfig{Server: "http://prod.mydomain.com"}, false, f); err != nil {
+		t.Fatal(err)
+	}
+	if err := deleteCluster("staging", f); err != nil {
+		t.Fatal(err)
+	}
+	c, err := readConfigFile(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, e := c.Clusters["staging"]; e {
+		t.Error("cluster staging should have been deleted")
+	}
+	if _, e := c.Clusters["prod"]; !e {
+		t.Error("cluster prod should have been kept")
+	}
+	if c.CurrentCluster != "" {
+		t.Errorf("current cluster should have been unset, got %s", c.CurrentCluster)
+	}
+	if err := deleteCluster("staging", f); err == nil {
+		t.Error("deleting an unknown cluster should fail")
+	}
+}
+
+func TestRenameCluster(t *testing.T) {
+	f, cleanup := newTempConfigFile(t)
+	defer cleanup()
+
+	if err := setCluster("staging", clusterConfig{Server: "http://staging.mydomain.com"}, true, f); err != nil {
+		t.Fatal(err)
+	}
+	if err := setCluster("prod", clusterConfig{Server: "http://prod.mydomain.com"}, false, f); err != nil {
+		t.Fatal(err)
+	}
+	if err := renameCluster("staging", "prod", f); err == nil {
+		t.Error("renaming over an existing cluster should fail")
+	}
+	if err := renameCluster("staging", "aws-staging", f); err != nil {
+		t.Fatal(err)
+	}
+	c, err := readConfigFile(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Clusters["aws-staging"].Server != "http://staging.mydomain.com" {
+		t.Errorf("expected the renamed cluster to keep its server, got %+v", c.Clusters)
+	}
+	if c.CurrentCluster != "aws-staging" {
+		t.Errorf("expected current cluster to follow the rename, got %s", c.CurrentCluster)
+	}
+}
+
+func TestConcurrentConfigUpdates(t *testing.T) {
+	f, cleanup := newTempConfigFile(t)
+	defer cleanup()
+
+	n := 20
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		go func(i int) {
+			errs <- setCluster(fmt.Sprintf("cluster-%d", i), clusterConfig{Server: "http://mydomain.com"}, false, f)
+		}(i)
+	}
+	for i := 0; i < n; i++ {
+		if err := <-errs; err != nil {
+			t.Fatal(err)
+		}
+	}
+	c, err := readConfigFile(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Clusters) != n {
+		t.Errorf("expected %d clusters, got %d", n, len(c.Clusters))
+	}
+}
+
+func TestClusterFromEnv(t *testing.T) {
+	defer os.Unsetenv("CLUSTER")
+	defer os.Unsetenv("TERESA_CLUSTER")
+	viper.AutomaticEnv()
+
+	os.Setenv("CLUSTER", "other")
+	if n, err := getCurrentClusterName(); err == nil {
+		t.Errorf("expected the CLUSTER env var to be ignored, got the cluster %s", n)
+	}
+	os.Setenv("TERESA_CLUSTER", "mine")
+	if n, err := getCurrentClusterName(); err != nil || n != "mine" {
+		t.Errorf("expected the cluster from TERESA_CLUSTER, got %q (err: %v)", n, err)
+	}
+
+	// the other settings keep their unprefixed env vars
+	defer os.Unsetenv("DEBUG")
+	os.Setenv("DEBUG", "true")
+	if !viper.GetBool("debug") {
+		t.Error("expected the DEBUG env var to be read")
+	}
+}
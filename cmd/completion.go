@@ -0,0 +1,304 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/luizalabs/teresa-api/models"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+var completionCmd = &cobra.Command{
+	Use:   "completion SHELL",
+	Short: "Generates the shell completion script",
+	Long: `Write the completion script for bash, zsh, fish or powershell to the
+standard output.
+
+Besides the commands and flags, the names of apps (--app), teams (--team)
+and clusters (--cluster) are completed. The app and team names come from
+the cluster given with --cluster in the command line, or TERESA_CLUSTER,
+or the current cluster, and are cached for a few minutes.
+
+To load the completion on every session:
+
+bash:
+
+	$ teresa completion bash > ~/.teresa/completion.bash
+	$ echo 'source ~/.teresa/completion.bash' >> ~/.bashrc
+
+zsh:
+
+	$ teresa completion zsh > "${fpath[1]}/_teresa"
+
+fish:
+
+	$ teresa completion fish > ~/.config/fish/completions/teresa.fish
+
+powershell:
+
+	PS> teresa completion powershell >> $PROFILE
+	`,
+	ValidArgs: []string{"bash", "zsh", "fish", "powershell"},
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 {
+			Usage(cmd)
+			return
+		}
+		var err error
+		switch args[0] {
+		case "bash":
+			err = genBashCompletion(RootCmd, os.Stdout)
+		case "zsh":
+			err = genZshCompletion(RootCmd, os.Stdout)
+		case "fish":
+			err = genFishCompletion(RootCmd, os.Stdout)
+		case "powershell":
+			err = genPowershellCompletion(RootCmd, os.Stdout)
+		default:
+			Fatalf(cmd, "Unsupported shell %s, use one of: %s", args[0], strings.Join(cmd.ValidArgs, ", "))
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+// prints the names completed by the completion scripts, one per line.
+// Errors are only logged, so the shell just gets no completion
+var completeNamesCmd = &cobra.Command{
+	Use:    "__complete-names apps|teams|clusters",
+	Hidden: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 {
+			return
+		}
+		names, err := completionNames(args[0])
+		if err != nil {
+			log.WithError(err).Debug("Failed to get the names to complete")
+			return
+		}
+		for _, n := range names {
+			fmt.Println(n)
+		}
+	},
+}
+
+// kinds of names completed for the flags
+var completionFlagNames = map[string]string{
+	"app":     "apps",
+	"team":    "teams",
+	"cluster": "clusters",
+}
+
+// kinds of names completed for the arguments of the commands, keyed by the
+// command path without the root
+var completionArgNames = map[string]string{
+	"config use-cluster":    "clusters",
+	"config delete-cluster": "clusters",
+	"config rename-cluster": "clusters",
+}
+
+// commands taking files or directories as arguments, only needed by the
+// shells not completing files by default
+var completionFileArgs = map[string]bool{
+	"deploy": true,
+}
+
+// the app and team names of the current cluster
+type completionCache struct {
+	Teams    []string  `yaml:"teams"`
+	Apps     []string  `yaml:"apps"`
+	CachedAt time.Time `yaml:"cached_at"`
+}
+
+func completionNames(kind string) ([]string, error) {
+	if kind == "clusters" {
+		c, err := readConfigFile(cfgFile)
+		if err != nil {
+			return nil, err
+		}
+		names := make([]string, 0, len(c.Clusters))
+		for n := range c.Clusters {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return names, nil
+	}
+	c, err := loadCompletionCache()
+	if err != nil {
+		return nil, err
+	}
+	switch kind {
+	case "apps":
+		return c.Apps, nil
+	case "teams":
+		return c.Teams, nil
+	}
+	return nil, fmt.Errorf("unknown names %s", kind)
+}
+
+// the app and team names of the user, from the cache when recent enough
+func loadCompletionCache() (*completionCache, error) {
+	cfg, err := readConfigFile(cfgFile)
+	if err != nil {
+		return nil, err
+	}
+	n, err := getCurrentClusterName()
+	if err != nil {
+		return nil, err
+	}
+	cluster, ok := cfg.Clusters[n]
+	if !ok {
+		return nil, fmt.Errorf("cluster %s not configured", n)
+	}
+	f := clusterCacheFile(cfgFile, n, ".names.yaml")
+	c := &completionCache{}
+	if y, err := ioutil.ReadFile(f); err == nil {
+		if err = yaml.Unmarshal(y, c); err == nil && time.Since(c.CachedAt) < resolverCacheTTL {
+			return c, nil
+		}
+	}
+
+	tc, err := newTeresaClient(cluster)
+	if err != nil {
+		return nil, err
+	}
+	tc.cluster = n
+	me, err := tc.Me()
+	if err != nil {
+		return nil, err
+	}
+	c = newCompletionCache(me.Teams)
+	if y, err := yaml.Marshal(c); err == nil {
+		writeFileAtomic(f, y)
+	}
+	return c, nil
+}
+
+func newCompletionCache(teams []*models.Team) *completionCache {
+	c := &completionCache{CachedAt: time.Now()}
+	apps := make(map[string]bool)
+	for _, t := range teams {
+		if t.Name != nil {
+			c.Teams = append(c.Teams, *t.Name)
+		}
+		for _, a := range t.Apps {
+			if a.Name != nil {
+				apps[*a.Name] = true
+			}
+		}
+	}
+	for a := range apps {
+		c.Apps = append(c.Apps, a)
+	}
+	sort.Strings(c.Teams)
+	sort.Strings(c.Apps)
+	return c
+}
+
+// annotate the app, team and cluster flags of every command, so the bash
+// completion calls the functions completing their names
+func markCompletionFlags(cmd *cobra.Command) {
+	for name, kind := range completionFlagNames {
+		if f := cmd.NonInheritedFlags().Lookup(name); f != nil {
+			f.Annotations = map[string][]string{cobra.BashCompCustom: {"__teresa_" + kind}}
+		}
+	}
+	for _, c := range cmd.Commands() {
+		markCompletionFlags(c)
+	}
+}
+
+// the command path without the root, like "config use-cluster"
+func completionPath(cmd *cobra.Command) string {
+	return strings.TrimPrefix(strings.TrimPrefix(cmd.CommandPath(), cmd.Root().Name()), " ")
+}
+
+func genBashCompletion(root *cobra.Command, w io.Writer) error {
+	markCompletionFlags(root)
+	root.BashCompletionFunction = bashCompletionFunction(root)
+	return root.GenBashCompletion(w)
+}
+
+// bash functions called by the completion script, to complete the names
+func bashCompletionFunction(root *cobra.Command) string {
+	var paths []string
+	for p := range completionArgNames {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	var cases []string
+	for _, p := range paths {
+		cases = append(cases, fmt.Sprintf(`        %s_%s)
+            __teresa_%s
+            ;;`, root.Name(), strings.Replace(p, " ", "_", -1), completionArgNames[p]))
+	}
+	return fmt.Sprintf(`__teresa_names()
+{
+    local names cluster i
+    # complete the names of the cluster given in the command line
+    cluster=()
+    for (( i=1; i < cword; i++ )); do
+        case "${words[i]}" in
+            --cluster=*)
+                cluster=(--cluster "${words[i]#--cluster=}")
+                ;;
+            --cluster)
+                cluster=(--cluster "${words[i+1]}")
+                ;;
+        esac
+    done
+    names=$(%[1]s "${cluster[@]}" __complete-names "$1" 2>/dev/null)
+    COMPREPLY=( $(compgen -W "${names}" -- "$cur") )
+}
+
+__teresa_apps()
+{
+    __teresa_names apps
+}
+
+__teresa_teams()
+{
+    __teresa_names teams
+}
+
+__teresa_clusters()
+{
+    __teresa_names clusters
+}
+
+__custom_func()
+{
+    case ${last_command} in
+%[2]s
+    esac
+}
+`, root.Name(), strings.Join(cases, "\n"))
+}
+
+// zsh runs the bash script through bashcompinit, replacing the functions
+// of the bash-completion package it uses
+func genZshCompletion(root *cobra.Command, w io.Writer) error {
+	if _, err := fmt.Fprintf(w, "#compdef %s\n", root.Name()); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, zshCompletionHead); err != nil {
+		return err
+	}
+	if err := genBashCompletion(root, w); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, zshCompletionTail)
+	return err
+}
+
+func init() {
+	RootCmd.AddCommand(completionCmd)
+	RootCmd.AddCommand(completeNamesCmd)
+}
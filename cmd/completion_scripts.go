@@ -0,0 +1,374 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// visit the available commands, parents before children
+func visitCompletionCommands(cmd *cobra.Command, visit func(*cobra.Command)) {
+	visit(cmd)
+	for _, c := range cmd.Commands() {
+		if c.IsAvailableCommand() {
+			visitCompletionCommands(c, visit)
+		}
+	}
+}
+
+// all the flags of the command, including the inherited ones
+func completionFlags(cmd *cobra.Command) []*pflag.Flag {
+	var flags []*pflag.Flag
+	add := func(f *pflag.Flag) {
+		if !f.Hidden {
+			flags = append(flags, f)
+		}
+	}
+	cmd.NonInheritedFlags().VisitAll(add)
+	cmd.InheritedFlags().VisitAll(add)
+	return flags
+}
+
+func genFishCompletion(root *cobra.Command, w io.Writer) error {
+	name := root.Name()
+	var paths []string
+	visitCompletionCommands(root, func(c *cobra.Command) {
+		if c != root {
+			paths = append(paths, fishQuote(completionPath(c)))
+		}
+	})
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, `# fish completion for %[1]s
+
+set -g __%[1]s_commands %[2]s
+
+# the subcommands typed so far, like "config use-cluster"
+function __%[1]s_command_path
+    set -l path
+    for w in (commandline -opc)[2..-1]
+        switch $w
+            case '-*'
+                continue
+        end
+        set -l next (string trim -- "$path $w")
+        if contains -- $next $__%[1]s_commands
+            set path $next
+        end
+    end
+    echo $path
+end
+
+function __%[1]s_using_command
+    set -l path (__%[1]s_command_path)
+    test "$path" = "$argv"
+end
+
+# the names of the cluster given in the command line, if any
+function __%[1]s_complete_names
+    set -l words (commandline -opc)
+    set -l cluster
+    for i in (seq 2 (count $words))
+        switch $words[$i]
+            case '--cluster=*'
+                set cluster --cluster (string replace -- '--cluster=' '' $words[$i])
+            case --cluster
+                if test $i -lt (count $words)
+                    set cluster --cluster $words[(math $i + 1)]
+                end
+        end
+    end
+    %[1]s $cluster __complete-names $argv 2>/dev/null
+end
+
+complete -c %[1]s -f
+`, name, strings.Join(paths, " "))
+
+	visitCompletionCommands(root, func(c *cobra.Command) {
+		cond := fishQuote("__" + name + "_using_command " + completionPath(c))
+		for _, sub := range c.Commands() {
+			if sub.IsAvailableCommand() {
+				fmt.Fprintf(&b, "complete -c %s -n %s -a %s -d %s\n", name, cond, fishQuote(sub.Name()), fishQuote(sub.Short))
+			}
+		}
+		if kind, ok := completionArgNames[completionPath(c)]; ok {
+			fmt.Fprintf(&b, "complete -c %s -n %s -a %s\n", name, cond, fishQuote("(__"+name+"_complete_names "+kind+")"))
+		}
+		if completionFileArgs[completionPath(c)] {
+			fmt.Fprintf(&b, "complete -c %s -n %s -F\n", name, cond)
+		}
+		for _, f := range completionFlags(c) {
+			fmt.Fprintf(&b, "complete -c %s -n %s -l %s", name, cond, f.Name)
+			if f.Shorthand != "" {
+				fmt.Fprintf(&b, " -s %s", f.Shorthand)
+			}
+			fmt.Fprintf(&b, " -d %s", fishQuote(f.Usage))
+			if kind, ok := completionFlagNames[f.Name]; ok {
+				fmt.Fprintf(&b, " -x -a %s", fishQuote("(__"+name+"_complete_names "+kind+")"))
+			} else if _, ok := f.Annotations[cobra.BashCompFilenameExt]; ok {
+				b.WriteString(" -r -F")
+			} else if f.NoOptDefVal == "" {
+				b.WriteString(" -r")
+			}
+			b.WriteString("\n")
+		}
+	})
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
+
+func genPowershellCompletion(root *cobra.Command, w io.Writer) error {
+	name := root.Name()
+	var commands, flags, names []string
+	visitCompletionCommands(root, func(c *cobra.Command) {
+		p := completionPath(c)
+		var subs []string
+		for _, sub := range c.Commands() {
+			if sub.IsAvailableCommand() {
+				subs = append(subs, psQuote(sub.Name()))
+			}
+		}
+		commands = append(commands, fmt.Sprintf("        %s = @(%s)", psQuote(p), strings.Join(subs, ", ")))
+
+		var fs []string
+		for _, f := range completionFlags(c) {
+			fs = append(fs, psQuote("--"+f.Name))
+			if kind, ok := completionFlagNames[f.Name]; ok {
+				names = append(names, fmt.Sprintf("        %s = %s", psQuote(p+"|--"+f.Name), psQuote(kind)))
+				if f.Shorthand != "" {
+					names = append(names, fmt.Sprintf("        %s = %s", psQuote(p+"|-"+f.Shorthand), psQuote(kind)))
+				}
+			}
+		}
+		flags = append(flags, fmt.Sprintf("        %s = @(%s)", psQuote(p), strings.Join(fs, ", ")))
+		if kind, ok := completionArgNames[p]; ok {
+			names = append(names, fmt.Sprintf("        %s = %s", psQuote(p+"|"), psQuote(kind)))
+		}
+	})
+	sort.Strings(names)
+
+	_, err := fmt.Fprintf(w, `# powershell completion for %[1]s
+
+Register-ArgumentCompleter -Native -CommandName %[1]s -ScriptBlock {
+    param($wordToComplete, $commandAst, $cursorPosition)
+
+    $commands = @{
+%[2]s
+    }
+    $flags = @{
+%[3]s
+    }
+    # names completed for the flags ("path|flag") and arguments ("path|")
+    $names = @{
+%[4]s
+    }
+
+    # the words before the one being completed
+    $words = @($commandAst.CommandElements | Select-Object -Skip 1 | ForEach-Object { $_.ToString() })
+    if ($wordToComplete -ne '' -and $words.Count -gt 0) {
+        $words = @($words | Select-Object -First ($words.Count - 1))
+    }
+
+    $path = ''
+    foreach ($w in $words) {
+        if ($w.StartsWith('-')) { continue }
+        $next = "$path $w".Trim()
+        if ($commands.ContainsKey($next)) { $path = $next }
+    }
+
+    # the names of the cluster given in the command line, if any
+    $cluster = @()
+    for ($i = 0; $i -lt $words.Count; $i++) {
+        if ($words[$i] -like '--cluster=*') {
+            $cluster = @('--cluster', $words[$i].Substring(10))
+        } elseif ($words[$i] -eq '--cluster' -and $i + 1 -lt $words.Count) {
+            $cluster = @('--cluster', $words[$i + 1])
+        }
+    }
+
+    $prev = ''
+    if ($words.Count -gt 0) { $prev = $words[$words.Count - 1] }
+    if ($names.ContainsKey("$path|$prev")) {
+        $candidates = @(& %[1]s @cluster __complete-names $names["$path|$prev"] 2>$null)
+    } elseif ($wordToComplete.StartsWith('-')) {
+        $candidates = $flags[$path]
+    } else {
+        $candidates = $commands[$path]
+        if ($names.ContainsKey("$path|")) {
+            $candidates += @(& %[1]s @cluster __complete-names $names["$path|"] 2>$null)
+        }
+    }
+
+    $candidates | Where-Object { $_ -like "$wordToComplete*" } | ForEach-Object {
+        [System.Management.Automation.CompletionResult]::new($_, $_, 'ParameterValue', $_)
+    }
+}
+`, name, strings.Join(commands, "\n"), strings.Join(flags, "\n"), strings.Join(names, "\n"))
+	return err
+}
+
+func psQuote(s string) string {
+	return "'" + strings.Replace(s, "'", "''", -1) + "'"
+}
+
+const zshCompletionHead = `
+__teresa_bash_source()
+{
+	alias shopt=':'
+	alias _expand=_bash_expand
+	alias _complete=_bash_comp
+	emulate -L sh
+	setopt kshglob noshglob braceexpand
+
+	source "$@"
+}
+
+__teresa_type()
+{
+	# -t is not supported by zsh
+	if [ "$1" == "-t" ]; then
+		shift
+		# fake bash 4 to disable "complete -o nospace"
+		if [ "$1" = "__teresa_compopt" ]; then
+			echo builtin
+			return 0
+		fi
+	fi
+	type "$@"
+}
+
+__teresa_compgen()
+{
+	local completions w
+	completions=( $(compgen "$@") ) || return $?
+
+	# filter by the given word as prefix
+	while [[ "$1" = -* && "$1" != -- ]]; do
+		shift
+		shift
+	done
+	if [[ "$1" == -- ]]; then
+		shift
+	fi
+	for w in "${completions[@]}"; do
+		if [[ "${w}" = "$1"* ]]; then
+			echo "${w}"
+		fi
+	done
+}
+
+__teresa_compopt()
+{
+	true # not supported by bashcompinit
+}
+
+__teresa_declare()
+{
+	if [ "$1" == "-F" ]; then
+		whence -w "$@"
+	else
+		builtin declare "$@"
+	fi
+}
+
+__teresa_ltrim_colon_completions()
+{
+	if [[ "$1" == *:* && "$COMP_WORDBREAKS" == *:* ]]; then
+		# remove the colon-word prefix from the completions
+		local colon_word=${1%${1##*:}}
+		local i=${#COMPREPLY[*]}
+		while [[ $((--i)) -ge 0 ]]; do
+			COMPREPLY[$i]=${COMPREPLY[$i]#"$colon_word"}
+		done
+	fi
+}
+
+__teresa_get_comp_words_by_ref()
+{
+	cur="${COMP_WORDS[COMP_CWORD]}"
+	prev="${COMP_WORDS[${COMP_CWORD}-1]}"
+	words=("${COMP_WORDS[@]}")
+	cword=("${COMP_CWORD[@]}")
+}
+
+__teresa_filedir()
+{
+	local RET OLD_IFS w qw
+
+	OLD_IFS="$IFS"
+	IFS=$'\n'
+	if [ "$1" = "-d" ]; then
+		shift
+		RET=( $(compgen -d) )
+	else
+		RET=( $(compgen -f) )
+	fi
+	IFS="$OLD_IFS"
+
+	for w in ${RET[@]}; do
+		if [[ ! "${w}" = "${cur}"* ]]; then
+			continue
+		fi
+		if eval "[[ \"\${w}\" = *.$1 || -d \"\${w}\" ]]"; then
+			qw="$(__teresa_quote "${w}")"
+			if [ -d "${w}" ]; then
+				COMPREPLY+=("${qw}/")
+			else
+				COMPREPLY+=("${qw}")
+			fi
+		fi
+	done
+}
+
+__teresa_quote()
+{
+	if [[ $1 == \'* || $1 == \"* ]]; then
+		# leave out the first character
+		printf %q "${1:1}"
+	else
+		printf %q "$1"
+	fi
+}
+
+autoload -U +X bashcompinit && bashcompinit
+
+# word boundary patterns for BSD or GNU sed
+LWORD='[[:<:]]'
+RWORD='[[:>:]]'
+if sed --help 2>&1 | grep -q GNU; then
+	LWORD='\<'
+	RWORD='\>'
+fi
+
+__teresa_convert_bash_to_zsh()
+{
+	sed \
+	-e 's/declare -F/whence -w/' \
+	-e 's/local \([a-zA-Z0-9_]*\)=/local \1; \1=/' \
+	-e 's/flags+=("\(--.*\)=")/flags+=("\1"); two_word_flags+=("\1")/' \
+	-e 's/must_have_one_flag+=("\(--.*\)=")/must_have_one_flag+=("\1")/' \
+	-e "s/${LWORD}_filedir${RWORD}/__teresa_filedir/g" \
+	-e "s/${LWORD}_get_comp_words_by_ref${RWORD}/__teresa_get_comp_words_by_ref/g" \
+	-e "s/${LWORD}__ltrim_colon_completions${RWORD}/__teresa_ltrim_colon_completions/g" \
+	-e "s/${LWORD}compgen${RWORD}/__teresa_compgen/g" \
+	-e "s/${LWORD}compopt${RWORD}/__teresa_compopt/g" \
+	-e "s/${LWORD}declare${RWORD}/__teresa_declare/g" \
+	-e "s/\\\$(type${RWORD}/\$(__teresa_type/g" \
+	<<'BASH_COMPLETION_EOF'
+`
+
+const zshCompletionTail = `
+BASH_COMPLETION_EOF
+}
+
+__teresa_bash_source <(__teresa_convert_bash_to_zsh)
+`
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/luizalabs/teresa-api/models"
+	"github.com/spf13/cobra"
+)
+
+func TestCompletionScripts(t *testing.T) {
+	var tests = []struct {
+		shell    string
+		gen      func(*cobra.Command, io.Writer) error
+		expected []string
+	}{
+		{"bash", genBashCompletion, []string{
+			`flags_completion+=("__teresa_apps")`,
+			`flags_completion+=("__teresa_teams")`,
+			"teresa_config_use-cluster)\n            __teresa_clusters",
+			`names=$(teresa "${cluster[@]}" __complete-names "$1" 2>/dev/null)`,
+		}},
+		{"zsh", genZshCompletion, []string{
+			"#compdef teresa",
+			"__teresa_bash_source <(__teresa_convert_bash_to_zsh)",
+		}},
+		{"fish", genFishCompletion, []string{
+			`complete -c teresa -n '__teresa_using_command deploy' -l app -s a -d 'app name [required]' -x -a '(__teresa_complete_names apps)'`,
+			`complete -c teresa -n '__teresa_using_command config use-cluster' -a '(__teresa_complete_names clusters)'`,
+			`complete -c teresa -n '__teresa_using_command config' -a 'use-cluster'`,
+			"teresa $cluster __complete-names $argv",
+		}},
+		{"powershell", genPowershellCompletion, []string{
+			`'deploy|--app' = 'apps'`,
+			`'deploy|-a' = 'apps'`,
+			`'config use-cluster|' = 'clusters'`,
+			`& teresa @cluster __complete-names $names["$path|$prev"]`,
+		}},
+	}
+	for _, tt := range tests {
+		var b bytes.Buffer
+		if err := tt.gen(RootCmd, &b); err != nil {
+			t.Fatalf("%s: %s", tt.shell, err)
+		}
+		for _, e := range tt.expected {
+			if !strings.Contains(b.String(), e) {
+				t.Errorf("%s: expected the script to contain %q", tt.shell, e)
+			}
+		}
+		if strings.Contains(b.String(), "__complete-names apps|teams|clusters") {
+			t.Errorf("%s: the hidden command shouldn't be completed", tt.shell)
+		}
+	}
+}
+
+func TestNewCompletionCache(t *testing.T) {
+	site, checkout, webapi, worker := "site", "checkout", "webapi", "worker"
+	c := newCompletionCache([]*models.Team{
+		{Name: &site, Apps: []*models.App{{Name: &webapi}}},
+		{Name: &checkout, Apps: []*models.App{{Name: &webapi}, {Name: &worker}}},
+	})
+	if strings.Join(c.Teams, ",") != "checkout,site" {
+		t.Errorf("expected the sorted teams, got %v", c.Teams)
+	}
+	if strings.Join(c.Apps, ",") != "webapi,worker" {
+		t.Errorf("expected the sorted and unique apps, got %v", c.Apps)
+	}
+}
@@ -30,6 +30,12 @@ but won't set it as the default. To do that, you must run:
 
 From that point on, teresa will use this cluster until you select
 another via: teresa config use-cluster another-cluster.
+
+To list, rename or remove the configured clusters:
+
+	$ teresa config get-clusters
+	$ teresa config rename-cluster aws-staging staging
+	$ teresa config delete-cluster staging
 	`,
 }
 
@@ -40,12 +46,36 @@ func init() {
 type clusterConfig struct {
 	Server string `yaml:"server"`
 	Token  string `yaml:"token"`
+	// PEM bundle trusted in addition to the system CAs
+	CAFile string `yaml:"ca_file,omitempty"`
+	// PEM client certificate and key, for clusters requiring mutual TLS
+	ClientCert            string `yaml:"client_cert,omitempty"`
+	ClientKey             string `yaml:"client_key,omitempty"`
+	InsecureSkipTLSVerify bool   `yaml:"insecure_skip_tls_verify,omitempty"`
+	// http, https or socks5 proxy, the HTTPS_PROXY env var is used if empty
+	ProxyURL string `yaml:"proxy_url,omitempty"`
+	// version of the api, appended to the server url. Defaults to v1
+	APIVersion string `yaml:"api_version,omitempty"`
+}
+
+const defaultAPIVersion = "v1"
+
+func (c clusterConfig) apiVersion() string {
+	if c.APIVersion == "" {
+		return defaultAPIVersion
+	}
+	return c.APIVersion
 }
 
 type configFile struct {
-	Version        string                   `yaml:"version"`
+	// version of the cli that last wrote the file
+	Version string `yaml:"version"`
+	// layout of the file, see configSchemaVersion
+	SchemaVersion  int                      `yaml:"schema_version"`
 	Clusters       map[string]clusterConfig `yaml:"clusters"`
 	CurrentCluster string                   `yaml:"current_cluster"`
+	// release index of the update command, overriding the built in one
+	UpdateURL string `yaml:"update_url,omitempty"`
 }
 
 // GetAuthToken is a convenience function to return the jwt token for
@@ -65,20 +95,20 @@ func GetAuthToken() string {
 
 // SetAuthToken Persists the jwt auth token on the config file, overwriting
 // the old value, if any
-func SetAuthToken(token string) (err error) {
-	cfg, err := readConfigFile(cfgFile)
-	if err != nil {
-		log.Fatal(err)
-	}
+func SetAuthToken(token string) error {
 	n, err := getCurrentClusterName()
 	if err != nil {
 		log.Fatal(err)
 	}
-	cluster := cfg.Clusters[n]
-	cluster.Token = token
-	cfg.Clusters[n] = cluster
-	err = writeConfigFile(cfgFile, cfg)
-	return
+	return updateConfigFile(cfgFile, func(c *configFile) error {
+		cluster, ok := c.Clusters[n]
+		if !ok {
+			return newSysError(fmt.Sprintf(`Cluster "%s" not configured yet`, n))
+		}
+		cluster.Token = token
+		c.Clusters[n] = cluster
+		return nil
+	})
 }
 
 // read the config file from disk
@@ -97,18 +127,24 @@ func readConfigFile(f string) (c *configFile, err error) {
 		log.WithError(err).WithField("cfgFile", f).Error("Error trying to unmarshal the config file")
 		return nil, err
 	}
+	if conf.SchemaVersion > configSchemaVersion {
+		return nil, newerConfigFileError(f, &conf)
+	}
+	if conf.Clusters == nil {
+		conf.Clusters = make(map[string]clusterConfig)
+	}
 	return &conf, nil
 }
 
 // return the config file loaded from disk or creates a new one (empty with the base needs)
 func readOrCreateConfigFile(f string) (c *configFile, err error) {
-	if c, err = readConfigFile(cfgFile); err == nil || !os.IsNotExist(err) {
+	if c, err = readConfigFile(f); err == nil || !os.IsNotExist(err) {
 		return
 	}
 
 	// set defaults
 	log.Debug("Config file not found... creating the base one")
-	conf := configFile{Version: version, Clusters: make(map[string]clusterConfig)}
+	conf := configFile{Version: version, SchemaVersion: configSchemaVersion, Clusters: make(map[string]clusterConfig)}
 	return &conf, nil
 }
 
@@ -122,16 +158,73 @@ func marshalConfigFile(c *configFile) (b *[]byte, err error) {
 	return &z, nil
 }
 
-// write the config file to disk in yaml format
-func writeConfigFile(f string, c *configFile) error {
+// read the config file (or create a new one), apply the changes and write
+// it back to disk, holding the config lock the whole time, so concurrent
+// invocations of the cli don't overwrite each other changes
+func updateConfigFile(f string, update func(c *configFile) error) error {
+	unlock, err := lockConfigFile(f)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	c, err := readOrCreateConfigFile(f)
+	if err != nil {
+		return err
+	}
+	if err = update(c); err != nil {
+		return err
+	}
+	return saveConfigFile(f, c)
+}
+
+// write the config file atomically. The caller must hold the config lock
+func saveConfigFile(f string, c *configFile) error {
 	// TODO: implement validate before writing
+	c.Version = version
+	c.SchemaVersion = configSchemaVersion
 	log.WithField("fileName", f).WithField("config", *c).Debug("Marshaling the config file to save")
 	b, err := marshalConfigFile(c)
 	if err != nil {
 		return err
 	}
-	// get config basepath
+	return writeFileAtomic(f, *b)
+}
+
+// write the data to a temp file on the same directory and rename it over
+// the target, so readers never see a partially written file
+func writeFileAtomic(f string, b []byte) error {
 	p := filepath.Dir(f)
+	if err := createConfigDir(p); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(p, filepath.Base(f)+".tmp")
+	if err != nil {
+		log.WithError(err).WithField("directory", p).Error("Error creating the temp file")
+		return err
+	}
+	// nothing to do if the rename already succeeded
+	defer os.Remove(tmp.Name())
+	if _, err = tmp.Write(b); err == nil {
+		err = tmp.Sync()
+	}
+	if cErr := tmp.Close(); err == nil {
+		err = cErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0600)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), f)
+	}
+	if err != nil {
+		log.WithError(err).WithField("fileName", f).Error("Error while writing the file to disk")
+		return err
+	}
+	return nil
+}
+
+// create the config basepath, if it doesn't exist yet
+func createConfigDir(p string) error {
 	d, err := os.Stat(p)
 	if err != nil {
 		if !os.IsNotExist(err) {
@@ -147,14 +240,21 @@ func writeConfigFile(f string, c *configFile) error {
 		log.WithField("directory", p).Error("Path exists, but isn't a directory")
 		return errors.New("Path exists, but isn't a directory")
 	}
-	if err = ioutil.WriteFile(f, *b, 0600); err != nil {
-		log.WithError(err).Error("Error while writing the config file to disk")
-	}
 	return nil
 }
 
-// get the name of the current cluster in the config file
+// get the name of the cluster in use: the one given by the --cluster flag or
+// the TERESA_CLUSTER env var, then the project file one, falling back to the
+// current cluster in the config file
 func getCurrentClusterName() (n string, err error) {
+	if n = viper.GetString("teresa_cluster"); n != "" {
+		log.WithField("cluster", n).Debug("Using cluster from flag or env")
+		return
+	}
+	if project != nil && project.Cluster != "" {
+		log.WithField("cluster", project.Cluster).Debug("Using cluster from the project file")
+		return project.Cluster, nil
+	}
 	n = viper.GetString("current_cluster")
 	if n == "" {
 		log.Debug("Cluster not set yet")
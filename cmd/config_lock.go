@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"time"
+)
+
+// how long to wait for another teresa process to release the config file
+const configLockTimeout = 10 * time.Second
+
+var errConfigLocked = errors.New("config file locked by another process")
+
+// acquire an exclusive advisory lock on the config file, waiting for other
+// processes holding it. The returned func releases the lock
+func lockConfigFile(f string) (unlock func(), err error) {
+	if err = createConfigDir(filepath.Dir(f)); err != nil {
+		return nil, err
+	}
+	l := f + ".lock"
+	deadline := time.Now().Add(configLockTimeout)
+	for {
+		unlock, err = tryLockFile(l)
+		if err != errConfigLocked {
+			if err != nil {
+				log.WithError(err).WithField("lockFile", l).Error("Error trying to lock the config file")
+			}
+			return
+		}
+		if time.Now().After(deadline) {
+			return nil, newSysError(fmt.Sprintf("Timed out waiting for the config file lock (%s)", l))
+		}
+		log.WithField("lockFile", l).Debug("Config file locked... waiting")
+		time.Sleep(50 * time.Millisecond)
+	}
+}
@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"gopkg.in/yaml.v2"
+)
+
+// layout version of the config file written by this cli. Every time the
+// layout changes, bump it and append the upgrade to configMigrations
+const configSchemaVersion = 2
+
+type configMigration struct {
+	description string
+	migrate     func(c map[string]interface{}) error
+}
+
+// configMigrations[i] upgrades a config file from schema i to i+1.
+// Migrations work on the raw yaml, because older layouts may not fit
+// the configFile struct anymore
+var configMigrations = []configMigration{
+	{
+		// files written before the schema_version field existed
+		description: "add the schema version",
+		migrate: func(c map[string]interface{}) error {
+			if c["clusters"] == nil {
+				c["clusters"] = map[string]interface{}{}
+			}
+			return nil
+		},
+	},
+	{
+		// the clusters got the optional ca_file, client_cert, client_key,
+		// insecure_skip_tls_verify, proxy_url and api_version settings.
+		// Nothing to change, but older clis would drop them rewriting the
+		// file, so they must refuse it
+		description: "add the cluster tls, proxy and api version settings",
+		migrate: func(c map[string]interface{}) error {
+			return nil
+		},
+	},
+}
+
+func newerConfigFileError(f string, c *configFile) error {
+	return newSysError(fmt.Sprintf(
+		"The config file %s was written by teresa %s (config version %d), but this cli only supports up to config version %d. Please upgrade the cli.",
+		f, c.Version, c.SchemaVersion, configSchemaVersion,
+	))
+}
+
+// upgrade the config file to the current layout, if it's an older one.
+// The original file is kept as a backup next to it
+func migrateConfigFile(f string) error {
+	if _, err := os.Stat(f); os.IsNotExist(err) {
+		return nil
+	}
+	unlock, err := lockConfigFile(f)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	y, err := ioutil.ReadFile(f)
+	if err != nil {
+		return err
+	}
+	raw := map[string]interface{}{}
+	if err = yaml.Unmarshal(y, &raw); err != nil {
+		log.WithError(err).WithField("cfgFile", f).Error("Error trying to unmarshal the config file")
+		return err
+	}
+	// files without schema_version are from before versioning (schema 0)
+	from, _ := raw["schema_version"].(int)
+	if from > configSchemaVersion {
+		v, _ := raw["version"].(string)
+		return newerConfigFileError(f, &configFile{Version: v, SchemaVersion: from})
+	}
+	if from == configSchemaVersion {
+		return nil
+	}
+
+	backup := fmt.Sprintf("%s.v%d.bak", f, from)
+	log.WithField("backup", backup).Infof("Upgrading the config file from version %d to %d", from, configSchemaVersion)
+	if err = writeFileAtomic(backup, y); err != nil {
+		return err
+	}
+	for v := from; v < configSchemaVersion; v++ {
+		m := configMigrations[v]
+		log.WithField("from", v).Debugf("Config migration: %s", m.description)
+		if err = m.migrate(raw); err != nil {
+			return fmt.Errorf("Failed to upgrade the config file to version %d: %s", v+1, err)
+		}
+		raw["schema_version"] = v + 1
+	}
+	raw["version"] = version
+
+	b, err := yaml.Marshal(raw)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(f, b)
+}
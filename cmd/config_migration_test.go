@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestMigrateLegacyConfigFile(t *testing.T) {
+	f, cleanup := newTempConfigFile(t)
+	defer cleanup()
+
+	legacy := []byte(`version: 0.1.2
+clusters:
+  staging:
+    server: http://staging.mydomain.com
+    token: foo
+current_cluster: staging
+`)
+	if err := ioutil.WriteFile(f, legacy, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := migrateConfigFile(f); err != nil {
+		t.Fatal(err)
+	}
+	c, err := readConfigFile(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.SchemaVersion != configSchemaVersion {
+		t.Errorf("expected schema version %d, got %d", configSchemaVersion, c.SchemaVersion)
+	}
+	if c.Clusters["staging"].Token != "foo" || c.CurrentCluster != "staging" {
+		t.Errorf("migration lost the config contents: %+v", c)
+	}
+	b, err := ioutil.ReadFile(f + ".v0.bak")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != string(legacy) {
+		t.Errorf("expected the backup to have the original contents, got %s", b)
+	}
+}
+
+func TestMigrateConfigFileV1(t *testing.T) {
+	f, cleanup := newTempConfigFile(t)
+	defer cleanup()
+
+	v1 := []byte(`version: 0.1.2
+schema_version: 1
+clusters:
+  staging:
+    server: http://staging.mydomain.com
+    token: foo
+current_cluster: staging
+`)
+	if err := ioutil.WriteFile(f, v1, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := migrateConfigFile(f); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(f + ".v1.bak"); err != nil {
+		t.Errorf("expected a backup of the version 1 file: %v", err)
+	}
+	if err := setCluster("prod", clusterConfig{Server: "https://prod.mydomain.com", ProxyURL: "socks5://proxy:1080", APIVersion: "v2"}, false, f); err != nil {
+		t.Fatal(err)
+	}
+	c, err := readConfigFile(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.SchemaVersion != 2 || c.Clusters["staging"].Token != "foo" {
+		t.Errorf("expected the version 1 file upgraded to 2, got %+v", c)
+	}
+	if p := c.Clusters["prod"]; p.ProxyURL != "socks5://proxy:1080" || p.APIVersion != "v2" {
+		t.Errorf("expected the proxy and api version saved, got %+v", p)
+	}
+}
+
+func TestMigrateNewerConfigFile(t *testing.T) {
+	f, cleanup := newTempConfigFile(t)
+	defer cleanup()
+
+	if err := ioutil.WriteFile(f, []byte("version: 9.0.0\nschema_version: 999\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := migrateConfigFile(f); err == nil {
+		t.Error("expected an error migrating a config file from a newer cli")
+	}
+	if _, err := readConfigFile(f); err == nil {
+		t.Error("expected an error reading a config file from a newer cli")
+	}
+}
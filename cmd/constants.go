@@ -3,21 +3,40 @@ package cmd
 // variables used to capture the cli flags
 var (
 	cfgFile            string
+	clusterFlag        string
 	serverFlag         string
+	caFileFlag         string
+	clientCertFlag     string
+	clientKeyFlag      string
+	insecureFlag       bool
+	proxyFlag          string
+	apiVersionFlag     string
+	skipCheckFlag      bool
+	onlyClientFlag     bool
+	refreshFlag        bool
+	checkFlag          bool
 	currentFlag        bool
 	teamIDFlag         int64
 	teamNameFlag       string
 	teamEmailFlag      string
 	teamURLFlag        string
+	teamRenameFlag     string
 	userIDFlag         int64
 	userNameFlag       string
 	userEmailFlag      string
 	userPasswordFlag   string
 	appNameFlag        string
 	appScaleFlag       int
+	appFileFlag        string
 	descriptionFlag    string
+	envExportFlag      string
 	autocompleteTarget string
 	isAdminFlag        bool
+	noAdminFlag        bool
+	yesFlag            bool
+	dryRunFlag         bool
+	applyFlag          bool
+	allFlag            bool
 )
 
 const (
@@ -25,4 +44,7 @@ const (
 	archiveTempFolder     = "/tmp"
 	deploymentSuccessMark = "----------deployment-success----------"
 	deploymentErrorMark   = "----------deployment-error----------"
+	// number of items fetched per request on the paginated endpoints
+	pageSize          = 50
+	minPasswordLength = 8
 )
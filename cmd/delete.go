@@ -5,8 +5,8 @@ import "github.com/spf13/cobra"
 // deleteCmd represents the delete command
 var deleteCmd = &cobra.Command{
 	Use:   "delete",
-	Short: "Delete team or user",
-	Long:  `Delete team or user.`,
+	Short: "Delete team, user or application",
+	Long:  `Delete team, user or application.`,
 }
 
 func init() {
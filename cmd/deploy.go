@@ -14,7 +14,7 @@ import (
 )
 
 var deployCmd = &cobra.Command{
-	Use:   "deploy APP_FOLDER",
+	Use:   "deploy [APP_FOLDER]",
 	Short: "Deploy an app",
 	Long: `Deploy an application.
 
@@ -26,19 +26,30 @@ eventually help on rollbacks.
 eg.:
 
   $ teresa deploy . --app webapi --team site --description "release 1.2 with new checkout"
+
+Inside a folder with a .teresa.yaml project file, the app, team and
+folder to deploy are taken from it:
+
+  $ teresa deploy
 	`,
 	Run: func(cmd *cobra.Command, args []string) {
-		if appNameFlag == "" && len(args) == 0 {
+		folder := ""
+		if len(args) > 0 {
+			folder = args[0]
+		} else if project != nil {
+			folder = project.deployPath()
+		}
+		if appNameFlag == "" && folder == "" {
 			Usage(cmd)
 			return
 		}
 		if appNameFlag == "" {
 			Fatalf(cmd, "app name required")
 		}
-		if len(args) == 0 || (len(args) > 0 && args[0] == "") {
+		if folder == "" {
 			Fatalf(cmd, "app folder required")
 		}
-		createDeploy(appNameFlag, teamNameFlag, descriptionFlag, args[0])
+		createDeploy(appNameFlag, teamNameFlag, descriptionFlag, folder)
 	},
 }
 
@@ -70,26 +81,31 @@ func createDeploy(appName, teamName, description, appFolder string) error {
 	tc := NewTeresa()
 	log.Infof("Getting app info from cluster %s", clusterName)
 	a := tc.GetAppInfo(teamName, appName)
+	if err = deployFolder(tc, a, appName, teamName, description, appFolder); err != nil {
+		log.Fatal(err)
+	}
+	return nil
+}
+
+// archive the folder and deploy it, streaming the deploy output to the log
+func deployFolder(tc TeresaClient, a AppInfo, appName, teamName, description, appFolder string) error {
 	// create and get the archive
 	log.Infof("Generating tarball of %s", appFolder)
 	tar, err := createTempArchiveToUpload(appName, teamName, appFolder)
 	if err != nil {
-		log.Fatalf("error creating the archive. %s", err)
+		return fmt.Errorf("error creating the archive. %s", err)
 	}
 	file, err := os.Open(tar)
 	if err != nil {
-		log.Fatalf("error getting the archive to upload. %s", err)
+		return fmt.Errorf("error getting the archive to upload. %s", err)
 	}
 	defer file.Close()
 
-	log.Infof("Deploying application to cluster `%s`", clusterName)
+	log.Infof("Deploying application to cluster `%s`", tc.cluster)
 
 	writer := &deploymentWriter{w: os.Stdout}
 	_, err = tc.CreateDeploy(a.TeamID, a.AppID, description, file, writer)
-	if err != nil {
-		log.Fatal(err.Error())
-	}
-	return nil
+	return err
 }
 
 // create a temporary archive file of the app to deploy and return the path of this file
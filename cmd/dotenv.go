@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/luizalabs/teresa-api/models"
+)
+
+// format the env vars as a dotenv file (KEY=value lines), sorted by key.
+// Values with spaces or special chars are double quoted
+func formatDotenv(l []*models.EnvVar) []byte {
+	vars := make([]*models.EnvVar, len(l))
+	copy(vars, l)
+	sort.Sort(envVarsByKey(vars))
+	var b bytes.Buffer
+	for _, e := range vars {
+		fmt.Fprintf(&b, "%s=%s\n", *e.Key, quoteDotenvValue(*e.Value))
+	}
+	return b.Bytes()
+}
+
+func quoteDotenvValue(v string) string {
+	if v == "" || strings.ContainsAny(v, " \t\r\n\"'#\\$`") {
+		return strconv.Quote(v)
+	}
+	return v
+}
+
+// parse a dotenv file, as written by formatDotenv. Blank lines and
+// comments are skipped and an "export " prefix is allowed. Double quoted
+// values are unquoted as go strings, single quoted ones taken as is
+func parseDotenv(b []byte) (map[string]string, error) {
+	vars := make(map[string]string)
+	for i, l := range strings.Split(string(b), "\n") {
+		l = strings.TrimSpace(l)
+		if l == "" || strings.HasPrefix(l, "#") {
+			continue
+		}
+		kv := strings.SplitN(strings.TrimPrefix(l, "export "), "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("line %d: expected KEY=value, got %q", i+1, l)
+		}
+		k, v := strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1])
+		if !envVarKeyRegexp.MatchString(k) {
+			return nil, fmt.Errorf("line %d: invalid key %q", i+1, k)
+		}
+		if _, ok := vars[k]; ok {
+			return nil, fmt.Errorf("line %d: duplicated key %s", i+1, k)
+		}
+		switch {
+		case strings.HasPrefix(v, `"`):
+			u, err := strconv.Unquote(v)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid quoted value %s", i+1, v)
+			}
+			v = u
+		case strings.HasPrefix(v, "'"):
+			if len(v) < 2 || !strings.HasSuffix(v, "'") {
+				return nil, fmt.Errorf("line %d: invalid quoted value %s", i+1, v)
+			}
+			v = v[1 : len(v)-1]
+		}
+		vars[k] = v
+	}
+	return vars, nil
+}
+
+type envVarsByKey []*models.EnvVar
+
+func (l envVarsByKey) Len() int           { return len(l) }
+func (l envVarsByKey) Swap(i, j int)      { l[i], l[j] = l[j], l[i] }
+func (l envVarsByKey) Less(i, j int) bool { return *l[i].Key < *l[j].Key }
@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/luizalabs/teresa-api/models"
+	"github.com/spf13/cobra"
+)
+
+var editCmd = &cobra.Command{
+	Use:   "edit",
+	Short: "Edit properties of an app in your editor",
+}
+
+var editEnvCmd = &cobra.Command{
+	Use:   "env",
+	Short: "Edit the env vars of an app in your editor",
+	Long: `Open the env vars of the app in your editor as a dotenv file (KEY=value
+lines). When the editor is closed, the changes are shown and applied at once,
+with a single restart of the app.
+
+Nothing is changed if the file is saved unchanged or with invalid lines.
+
+The editor is taken from the VISUAL or EDITOR env vars, defaulting to vi.
+
+eg.:
+
+	$ teresa edit env --app webapi --team site
+	$ EDITOR=nano teresa edit env --app webapi
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if appNameFlag == "" {
+			Usage(cmd)
+			return
+		}
+		tc := NewTeresa()
+		a := tc.GetAppInfo(teamNameFlag, appNameFlag)
+		app, err := tc.GetAppDetail(a.TeamID, a.AppID)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		set, unset, err := editEnvVars(*app.Name, app.EnvVars, runEditor)
+		if err == errEditUnchanged {
+			fmt.Println("Edit cancelled, no changes made.")
+			return
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		if len(set) == 0 && len(unset) == 0 {
+			fmt.Println("No changes to the env vars.")
+			return
+		}
+		fmt.Printf("Changes to the env vars of %s:\n", *app.Name)
+		printEnvVarsDiff(os.Stdout, set, unset)
+		if !yesFlag && !confirm("Apply the changes?") {
+			log.Fatal("Aborted, no changes made")
+		}
+		if err = tc.PartialUpdateApp(a.TeamID, a.AppID, envVarsPatch(set, unset)); err != nil {
+			log.Fatalf("Failed to update the env vars: %s", err)
+		}
+		log.Infof("Env vars of %s updated", *app.Name)
+	},
+}
+
+var errEditUnchanged = errors.New("file unchanged")
+
+// write the env vars to a temp dotenv file, let the user edit it and return
+// the changes. The file is kept when its content is invalid, so the edits
+// aren't lost
+func editEnvVars(appName string, current []*models.EnvVar, edit func(f string) error) (set []*models.EnvVar, unset []string, err error) {
+	// a private dir keeps the file named after the app, so the editor
+	// shows it and picks the dotenv syntax
+	dir, err := ioutil.TempDir("", "teresa-edit")
+	if err != nil {
+		return nil, nil, err
+	}
+	f := filepath.Join(dir, appName+".env")
+	header := fmt.Sprintf("# Env vars of the app %s, one KEY=value per line.\n# Remove a line to unset the var. Lines starting with # are ignored.\n\n", appName)
+	before := append([]byte(header), formatDotenv(current)...)
+	if err = ioutil.WriteFile(f, before, 0600); err != nil {
+		os.RemoveAll(dir)
+		return nil, nil, err
+	}
+
+	if err = edit(f); err != nil {
+		os.RemoveAll(dir)
+		return nil, nil, fmt.Errorf("Failed to run the editor: %s", err)
+	}
+	after, err := ioutil.ReadFile(f)
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, nil, err
+	}
+	if bytes.Equal(before, after) {
+		os.RemoveAll(dir)
+		return nil, nil, errEditUnchanged
+	}
+	desired, err := parseDotenv(after)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Invalid env vars, no changes made (your edits were kept in %s): %s", f, err)
+	}
+	os.RemoveAll(dir)
+	set, unset = diffEnvVars(current, desired)
+	return set, unset, nil
+}
+
+// the editor command of the user, with its arguments. VISUAL comes first,
+// as usual for full screen editors
+func editorCommand() []string {
+	for _, v := range []string{"VISUAL", "EDITOR"} {
+		if e := strings.Fields(os.Getenv(v)); len(e) > 0 {
+			return e
+		}
+	}
+	return []string{"vi"}
+}
+
+func runEditor(f string) error {
+	e := editorCommand()
+	c := exec.Command(e[0], append(e[1:], f)...)
+	c.Stdin, c.Stdout, c.Stderr = os.Stdin, os.Stdout, os.Stderr
+	return c.Run()
+}
+
+func init() {
+	RootCmd.AddCommand(editCmd)
+	editCmd.AddCommand(editEnvCmd)
+	editEnvCmd.Flags().StringVar(&appNameFlag, "app", "", "app name [required]")
+	editEnvCmd.Flags().StringVar(&teamNameFlag, "team", "", "team name")
+	editEnvCmd.Flags().BoolVarP(&yesFlag, "yes", "y", false, "don't ask for confirmation")
+}
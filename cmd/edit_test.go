@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/luizalabs/teresa-api/models"
+)
+
+func newTestEnvVars(kv ...string) []*models.EnvVar {
+	var l []*models.EnvVar
+	for i := 0; i < len(kv); i += 2 {
+		l = append(l, &models.EnvVar{Key: &kv[i], Value: &kv[i+1]})
+	}
+	return l
+}
+
+func TestParseDotenv(t *testing.T) {
+	vars := newTestEnvVars("PLAIN", "value", "SPACES", "a b", "QUOTES", `say "hi"`, "LINES", "a\nb", "EMPTY", "")
+	parsed, err := parseDotenv(formatDotenv(vars))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range vars {
+		if parsed[*e.Key] != *e.Value {
+			t.Errorf("expected %s to be %q, got %q", *e.Key, *e.Value, parsed[*e.Key])
+		}
+	}
+
+	parsed, err = parseDotenv([]byte("# comment\n\nexport A=1\nB='$HOME'\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed["A"] != "1" || parsed["B"] != "$HOME" || len(parsed) != 2 {
+		t.Errorf("unexpected vars %v", parsed)
+	}
+
+	for _, s := range []string{"NOVALUE", "1KEY=x", "A=1\nA=2", `A="unterminated`, "A='x"} {
+		if _, err := parseDotenv([]byte(s)); err == nil {
+			t.Errorf("expected an error for %q", s)
+		}
+	}
+}
+
+func TestEditEnvVars(t *testing.T) {
+	current := newTestEnvVars("KEEP", "1", "CHANGE", "old", "REMOVE", "x")
+	writeFile := func(content string) func(string) error {
+		return func(f string) error {
+			return ioutil.WriteFile(f, []byte(content), 0600)
+		}
+	}
+
+	set, unset, err := editEnvVars("webapi", current, writeFile("KEEP=1\nCHANGE=new\nADD=\"a b\"\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(set) != 2 || *set[0].Key != "ADD" || *set[0].Value != "a b" || *set[1].Key != "CHANGE" || *set[1].Value != "new" {
+		t.Errorf("expected ADD and CHANGE to be set, got %v", set)
+	}
+	if len(unset) != 1 || unset[0] != "REMOVE" {
+		t.Errorf("expected REMOVE to be unset, got %v", unset)
+	}
+
+	if _, _, err = editEnvVars("webapi", current, func(string) error { return nil }); err != errEditUnchanged {
+		t.Errorf("expected the unchanged error, got %v", err)
+	}
+
+	_, _, err = editEnvVars("webapi", current, writeFile("not a var\n"))
+	if err == nil || !strings.Contains(err.Error(), "line 1") {
+		t.Fatalf("expected an invalid line error, got %v", err)
+	}
+	// the edits are kept for the user
+	kept := err.Error()[strings.Index(err.Error(), "kept in ")+len("kept in ") : strings.Index(err.Error(), "):")]
+	if b, rErr := ioutil.ReadFile(kept); rErr != nil || string(b) != "not a var\n" {
+		t.Errorf("expected the edits to be kept in %s, got %q (%v)", kept, b, rErr)
+	}
+	os.RemoveAll(filepath.Dir(kept))
+
+	if _, _, err = editEnvVars("webapi", current, func(string) error { return errors.New("not found") }); err == nil {
+		t.Error("expected the editor error")
+	}
+}
+
+func TestEditorCommand(t *testing.T) {
+	defer func(v, e string) { os.Setenv("VISUAL", v); os.Setenv("EDITOR", e) }(os.Getenv("VISUAL"), os.Getenv("EDITOR"))
+
+	var tests = []struct {
+		visual, editor string
+		expected       string
+	}{
+		{"code --wait", "nano", "code --wait"},
+		{"", "nano", "nano"},
+		{"", "", "vi"},
+	}
+	for _, tt := range tests {
+		os.Setenv("VISUAL", tt.visual)
+		os.Setenv("EDITOR", tt.editor)
+		if e := strings.Join(editorCommand(), " "); e != tt.expected {
+			t.Errorf("expected %q with VISUAL=%q and EDITOR=%q, got %q", tt.expected, tt.visual, tt.editor, e)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"sort"
+
+	"github.com/luizalabs/teresa-api/models"
+)
+
+// compare the current env vars of an app with the desired ones, returning
+// the vars to add or update and the keys to remove, both sorted by key
+func diffEnvVars(current []*models.EnvVar, desired map[string]string) (set []*models.EnvVar, unset []string) {
+	cur := make(map[string]string, len(current))
+	for _, e := range current {
+		cur[*e.Key] = *e.Value
+		if _, ok := desired[*e.Key]; !ok {
+			unset = append(unset, *e.Key)
+		}
+	}
+	for k, v := range desired {
+		if old, ok := cur[k]; !ok || old != v {
+			key, value := k, v
+			set = append(set, &models.EnvVar{Key: &key, Value: &value})
+		}
+	}
+	sort.Sort(envVarsByKey(set))
+	sort.Strings(unset)
+	return
+}
+
+// build the json patch operations to add and remove env vars of an app,
+// so all changes are applied with a single update
+func envVarsPatch(set []*models.EnvVar, unset []string) []*models.PatchAppRequest {
+	var ops []*models.PatchAppRequest
+	path := "/envvars"
+	if len(set) > 0 {
+		evars := make([]*models.PatchAppEnvVar, len(set))
+		for i, e := range set {
+			evars[i] = &models.PatchAppEnvVar{Key: e.Key, Value: *e.Value}
+		}
+		add := "add"
+		ops = append(ops, &models.PatchAppRequest{Op: &add, Path: &path, Value: evars})
+	}
+	if len(unset) > 0 {
+		evars := make([]*models.PatchAppEnvVar, len(unset))
+		for i := range unset {
+			evars[i] = &models.PatchAppEnvVar{Key: &unset[i]}
+		}
+		remove := "remove"
+		ops = append(ops, &models.PatchAppRequest{Op: &remove, Path: &path, Value: evars})
+	}
+	return ops
+}
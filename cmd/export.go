@@ -0,0 +1,206 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/luizalabs/teresa-api/models"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export teams, members and apps to yaml",
+	Long: `Export the teams, with their members, apps, scale and env vars.
+
+The output can be imported on another cluster with "teresa import", or used
+as a backup. eg.:
+
+	$ teresa export --team my_team > state.yaml
+	$ teresa export --all > state.yaml
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if teamNameFlag == "" && !allFlag {
+			Fatalf(cmd, "provide a team name or --all")
+		}
+		tc := NewTeresa()
+		var teams []*models.Team
+		if allFlag {
+			l, err := tc.GetTeams()
+			if err != nil {
+				log.Fatalf("Failed to retrieve teams: %s", err)
+			}
+			teams = l
+		} else {
+			t, err := tc.GetTeamByName(teamNameFlag)
+			if err != nil {
+				log.Fatal(err)
+			}
+			teams = []*models.Team{t}
+		}
+		spec, err := exportTeams(tc, teams)
+		if err != nil {
+			log.Fatal(err)
+		}
+		y, err := yaml.Marshal(spec)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Print(string(y))
+	},
+}
+
+var importCmd = &cobra.Command{
+	Use:   "import -f file",
+	Short: "Import teams, members and apps from yaml",
+	Long: `Create the teams, members and apps exported by "teresa export".
+
+Missing teams and apps are created, the email and url of the existing teams
+are updated, members are added to the teams and the apps scale and env vars
+are updated. Users can't be exported with their
+passwords, so they must be created on the cluster beforehand.
+
+By default nothing is changed, only the changes are shown. To run them:
+
+	$ teresa import -f state.yaml --cluster new_cluster
+	$ teresa import -f state.yaml --cluster new_cluster --apply
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if appFileFlag == "" {
+			Usage(cmd)
+			return
+		}
+		spec, err := readApplySpec(appFileFlag)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if msgs := spec.validate(); len(msgs) > 0 {
+			log.Fatalf("Invalid spec %s:\n  %s", appFileFlag, strings.Join(msgs, "\n  "))
+		}
+		tc := NewTeresa()
+		plan, err := planImport(tc, spec)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if len(plan) == 0 {
+			log.Info("Nothing to change")
+			return
+		}
+		printPlan(plan)
+		if !applyFlag {
+			log.Info("Dry run, nothing was changed. Run with --apply to import")
+			return
+		}
+		if err := runPlan(tc, plan); err != nil {
+			log.Fatal(err)
+		}
+		log.Info("Import finished")
+	},
+}
+
+// build the spec of the teams, with their members and apps
+func exportTeams(tc TeresaClient, teams []*models.Team) (*applySpec, error) {
+	spec := &applySpec{}
+	for _, t := range teams {
+		log.WithField("team", *t.Name).Debug("Exporting team")
+		team, err := tc.GetTeamDetail(t.ID)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to retrieve the team %s: %s", *t.Name, err)
+		}
+		ts := teamSpec{Name: *team.Name, Email: string(team.Email), URL: team.URL}
+		for _, u := range team.Members {
+			ts.Members = append(ts.Members, *u.Email)
+		}
+		apps, err := tc.GetApps(team.ID)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to retrieve the apps of team %s: %s", *team.Name, err)
+		}
+		for _, a := range apps {
+			app, err := tc.GetAppDetail(team.ID, a.ID)
+			if err != nil {
+				return nil, fmt.Errorf("Failed to retrieve the app %s/%s: %s", *team.Name, *a.Name, err)
+			}
+			as := appSpec{Name: *app.Name, Scale: app.Scale, Env: make(map[string]string, len(app.EnvVars))}
+			for _, e := range app.EnvVars {
+				as.Env[*e.Key] = *e.Value
+			}
+			ts.Apps = append(ts.Apps, as)
+		}
+		spec.Teams = append(spec.Teams, ts)
+	}
+	return spec, nil
+}
+
+// compute the actions to create the teams, members and apps of the spec
+func planImport(tc TeresaClient, spec *applySpec) ([]applyAction, error) {
+	l, err := tc.GetTeams()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to retrieve teams: %s", err)
+	}
+	existing := make(map[string]int64, len(l))
+	for _, t := range l {
+		existing[*t.Name] = t.ID
+	}
+
+	var plan []applyAction
+	for i := range spec.Teams {
+		ts := &spec.Teams[i]
+		id, ok := existing[ts.Name]
+		if !ok {
+			plan = append(plan, applyAction{kind: actionCreateTeam, team: ts.Name, teamSpec: ts})
+			for _, m := range ts.Members {
+				plan = append(plan, applyAction{kind: actionAddMember, team: ts.Name, member: m})
+			}
+			for j := range ts.Apps {
+				plan = append(plan, planApp(ts.Name, 0, nil, &ts.Apps[j])...)
+			}
+			continue
+		}
+
+		team, err := tc.GetTeamDetail(id)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to retrieve the team %s: %s", ts.Name, err)
+		}
+		// an email or url left out of the spec is kept
+		var changes teamChanges
+		if ts.Email != "" && ts.Email != string(team.Email) {
+			changes.email = &ts.Email
+		}
+		if ts.URL != "" && ts.URL != team.URL {
+			changes.url = &ts.URL
+		}
+		if !changes.empty() {
+			updated, err := changes.apply(team)
+			if err != nil {
+				return nil, fmt.Errorf("Failed to update the team %s: %s", ts.Name, err)
+			}
+			plan = append(plan, applyAction{kind: actionUpdateTeam, team: ts.Name, teamID: id, current: team, updated: updated})
+		}
+		members := make(map[string]bool, len(team.Members))
+		for _, u := range team.Members {
+			members[*u.Email] = true
+		}
+		for _, m := range ts.Members {
+			if !members[m] {
+				plan = append(plan, applyAction{kind: actionAddMember, team: ts.Name, member: m})
+			}
+		}
+		p, err := planTeamApps(tc, ts, id)
+		if err != nil {
+			return nil, err
+		}
+		plan = append(plan, p...)
+	}
+	return plan, nil
+}
+
+func init() {
+	exportCmd.Flags().StringVar(&teamNameFlag, "team", "", "team to export")
+	exportCmd.Flags().BoolVar(&allFlag, "all", false, "export all the teams")
+	RootCmd.AddCommand(exportCmd)
+
+	importCmd.Flags().StringVarP(&appFileFlag, "file", "f", "", "yaml exported by teresa export, - for stdin [required]")
+	importCmd.Flags().BoolVar(&applyFlag, "apply", false, "run the changes, instead of only showing them")
+	RootCmd.AddCommand(importCmd)
+}
@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+// api with the team site, its member and more than a page of apps
+func newExportTestAPI() *fakeAPI {
+	api := &fakeAPI{
+		responses: map[string]string{
+			"/v1/teams/1": `{"id": 1, "name": "site", "email": "site@luizalabs.com", "members": [{"name": "John", "email": "john@luizalabs.com", "isAdmin": false}]}`,
+		},
+		lists: map[string][]string{
+			"/v1/teams":        {`{"id": 1, "name": "site", "email": "site@luizalabs.com"}`},
+			"/v1/teams/1/apps": newTestItems("app", pageSize+1),
+		},
+	}
+	for i := 0; i <= pageSize; i++ {
+		api.responses[fmt.Sprintf("/v1/teams/1/apps/%d", i+1)] = fmt.Sprintf(
+			`{"id": %d, "name": "app%d", "scale": 2, "envVars": [{"key": "FOO", "value": "bar%d"}]}`, i+1, i, i)
+	}
+	return api
+}
+
+func TestExportImport(t *testing.T) {
+	tc, ts := newTestClient(t, newExportTestAPI())
+	defer ts.Close()
+
+	teams, err := tc.GetTeams()
+	if err != nil {
+		t.Fatal(err)
+	}
+	exported, err := exportTeams(tc, teams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// through yaml, like export > file and import -f file
+	y, err := yaml.Marshal(exported)
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec := &applySpec{}
+	if err = yaml.Unmarshal(y, spec); err != nil {
+		t.Fatal(err)
+	}
+	if msgs := spec.validate(); len(msgs) > 0 {
+		t.Fatalf("expected a valid export, got %v", msgs)
+	}
+	if len(spec.Teams) != 1 || len(spec.Teams[0].Apps) != pageSize+1 || len(spec.Teams[0].Members) != 1 {
+		t.Fatalf("expected the team with its member and %d apps, got %+v", pageSize+1, spec.Teams)
+	}
+	last := spec.Teams[0].Apps[pageSize]
+	if last.scale() != 2 || last.Env["FOO"] != fmt.Sprintf("bar%d", pageSize) {
+		t.Errorf("expected the scale and env vars of the apps, got %+v", last)
+	}
+
+	// on an empty cluster everything is created
+	empty, ets := newTestClient(t, &fakeAPI{lists: map[string][]string{"/v1/teams": nil}})
+	defer ets.Close()
+	plan, err := planImport(empty, spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan) != pageSize+3 {
+		t.Fatalf("expected the team, member and %d apps created, got %d actions", pageSize+1, len(plan))
+	}
+	if plan[0].kind != actionCreateTeam || plan[0].teamSpec.Email != "site@luizalabs.com" || plan[1].kind != actionAddMember || plan[1].member != "john@luizalabs.com" {
+		t.Errorf("expected the team and its member created first, got %v and %v", plan[0], plan[1])
+	}
+	for _, a := range plan[2:] {
+		if a.kind != actionCreateApp || a.spec.scale() != 2 {
+			t.Errorf("expected the apps created with their scale, got %v", a)
+		}
+	}
+
+	// on the exported cluster there's nothing to change
+	if plan, err = planImport(tc, spec); err != nil || len(plan) != 0 {
+		t.Errorf("expected no changes importing on the same cluster, got %v (err: %v)", plan, err)
+	}
+}
+
+func TestImportTeamChanges(t *testing.T) {
+	api := newExportTestAPI()
+	api.responses["PUT /v1/teams/1"] = `{"id": 1, "name": "site", "email": "dev@luizalabs.com"}`
+	tc, ts := newTestClient(t, api)
+	defer ts.Close()
+
+	// without email and url the team is kept as it is
+	spec := &applySpec{Teams: []teamSpec{{Name: "site"}}}
+	if plan, err := planImport(tc, spec); err != nil || len(plan) != 0 {
+		t.Fatalf("expected no changes for a team without email and url, got %v (err: %v)", plan, err)
+	}
+
+	spec.Teams[0].Email = "dev@luizalabs.com"
+	plan, err := planImport(tc, spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan) != 1 || plan[0].kind != actionUpdateTeam || plan[0].String() != "~ update team site\n    ~ email: site@luizalabs.com -> dev@luizalabs.com" {
+		t.Fatalf("expected the team email updated, got %v", plan)
+	}
+	api.requests = nil
+	if err = runPlan(tc, plan); err != nil {
+		t.Fatal(err)
+	}
+	if len(api.requests) != 1 || !strings.HasPrefix(api.requests[0], "PUT /v1/teams/1 ") || !strings.Contains(api.requests[0], `"email":"dev@luizalabs.com"`) {
+		t.Errorf("expected the team updated with the new email, got %v", api.requests)
+	}
+
+	spec.Teams[0].Email = "not an email"
+	if _, err = planImport(tc, spec); err == nil || !strings.Contains(err.Error(), "Invalid team email") {
+		t.Errorf("expected an error for an invalid email, got %v", err)
+	}
+}
@@ -5,7 +5,7 @@ import "github.com/spf13/cobra"
 // createCmd represents the create command
 var getCmd = &cobra.Command{
 	Use:   "get",
-	Short: "Get app details",
+	Short: "Get app, team and user details",
 	Long: `Get application details or get teams.
 
 To get details about an application:
@@ -15,6 +15,18 @@ To get details about an application:
 To get the teams you belong to:
 
 	$ teresa get teams
+
+To get the members, apps and latest deployments of a team:
+
+	$ teresa get team my_team
+
+To get the members of a team:
+
+	$ teresa get team-users --team my_team
+
+To get the users (admins only):
+
+	$ teresa get users
 	`,
 }
 
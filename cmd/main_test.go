@@ -0,0 +1,14 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	// the logger is created by cobra on command execution, not on import
+	initLog()
+	log.Out = ioutil.Discard
+	os.Exit(m.Run())
+}
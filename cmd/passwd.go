@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/go-openapi/strfmt"
+	"github.com/howeyc/gopass"
+	"github.com/luizalabs/teresa-api/client/auth"
+	"github.com/spf13/cobra"
+)
+
+var passwdCmd = &cobra.Command{
+	Use:   "passwd",
+	Short: "Change your password",
+	Long: `Change the password of the logged in user.
+
+The current password is asked to confirm it's you, and the new one must be
+at least 8 characters long and typed twice:
+
+	$ teresa passwd
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		tc := NewTeresa()
+		me, err := tc.Me()
+		if err != nil {
+			log.Fatalf("unable to get user information: %s", err)
+		}
+
+		current, err := readPassword("Current password: ")
+		if err != nil {
+			passwdInputError(err)
+		}
+		if err = checkPassword(tc, *me.Email, current); err != nil {
+			log.Fatal(err)
+		}
+		p, err := readPassword("New password: ")
+		if err != nil {
+			passwdInputError(err)
+		}
+		if len(p) < minPasswordLength {
+			log.Fatalf("The password must be at least %d characters long", minPasswordLength)
+		}
+		confirmation, err := readPassword("Confirm the new password: ")
+		if err != nil {
+			passwdInputError(err)
+		}
+		if p != confirmation {
+			log.Fatal("The passwords don't match")
+		}
+
+		me.Password = &p
+		me.Teams = nil
+		if _, err = tc.UpdateUser(me); err != nil {
+			log.Fatalf("Failed to change the password: %s", err)
+		}
+		log.Info("Password changed")
+
+		// login again, in case the server invalidates the old tokens
+		token, err := tc.Login(strfmt.Email(*me.Email), strfmt.Password(p))
+		if err != nil {
+			log.Warnf("Failed to login with the new password: %s", err)
+			return
+		}
+		if err := SetAuthToken(token); err != nil {
+			log.Fatalf("Failed to update the auth token: %s\n", err)
+		}
+	},
+}
+
+// check the password logging in, only an unauthorized login means it's wrong
+func checkPassword(tc TeresaClient, email, password string) error {
+	_, err := tc.Login(strfmt.Email(email), strfmt.Password(password))
+	if _, ok := err.(*auth.UserLoginUnauthorized); ok {
+		return newSysError("Wrong password")
+	}
+	if err != nil {
+		return fmt.Errorf("Failed to check the password: %s", err)
+	}
+	return nil
+}
+
+func passwdInputError(err error) {
+	if err == gopass.ErrInterrupted {
+		log.Fatal("Interrupted, the password was not changed")
+	}
+	log.Fatalf("Error trying to get the password: %s", err)
+}
+
+func init() {
+	RootCmd.AddCommand(passwdCmd)
+}
@@ -0,0 +1,30 @@
+package cmd
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestCheckPassword(t *testing.T) {
+	var tests = []struct {
+		status   int
+		expected string
+	}{
+		{http.StatusOK, ""},
+		{http.StatusUnauthorized, "Wrong password"},
+		{http.StatusInternalServerError, "Failed to check the password"},
+	}
+	for _, tt := range tests {
+		tc, ts := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(tt.status)
+			w.Write([]byte(`{"token": "token", "code": 0, "message": "error"}`))
+		}))
+		err := checkPassword(tc, "john@luizalabs.com", "secret")
+		ts.Close()
+		if tt.expected == "" && err != nil || tt.expected != "" && (err == nil || !strings.Contains(err.Error(), tt.expected)) {
+			t.Errorf("expected %q for the status %d, got %v", tt.expected, tt.status, err)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+// name of the project file, looked up from the working dir upwards
+const projectFileName = ".teresa.yaml"
+
+type projectDeployConfig struct {
+	// folder to deploy, relative to the project file
+	Path        string `yaml:"path"`
+	Description string `yaml:"description"`
+}
+
+// defaults for the commands run inside a project
+type projectConfig struct {
+	Cluster string              `yaml:"cluster"`
+	Team    string              `yaml:"team"`
+	App     string              `yaml:"app"`
+	Deploy  projectDeployConfig `yaml:"deploy"`
+
+	// directory where the project file was found
+	dir string
+}
+
+// project file found for the current working dir, if any
+var project *projectConfig
+
+func initProject() {
+	wd, err := os.Getwd()
+	if err != nil {
+		log.WithError(err).Debug("Failed to get the working dir, skipping the project file")
+		return
+	}
+	f, err := findProjectFile(wd)
+	if err != nil || f == "" {
+		return
+	}
+	p, err := readProjectFile(f)
+	if err != nil {
+		log.WithError(err).WithField("projectFile", f).Fatal("Error with the project file")
+	}
+	log.WithField("projectFile", f).Debugf("Project settings %+v", *p)
+	project = p
+}
+
+// return the path of the nearest project file, going up from dir,
+// or an empty string if there is none
+func findProjectFile(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for {
+		f := filepath.Join(dir, projectFileName)
+		if _, err := os.Stat(f); err == nil {
+			return f, nil
+		} else if !os.IsNotExist(err) {
+			return "", err
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+func readProjectFile(f string) (*projectConfig, error) {
+	y, err := ioutil.ReadFile(f)
+	if err != nil {
+		return nil, err
+	}
+	p := projectConfig{}
+	if err = yaml.Unmarshal(y, &p); err != nil {
+		return nil, err
+	}
+	p.dir = filepath.Dir(f)
+	return &p, nil
+}
+
+// folder to deploy when none is given
+func (p *projectConfig) deployPath() string {
+	if filepath.IsAbs(p.Deploy.Path) {
+		return p.Deploy.Path
+	}
+	return filepath.Join(p.dir, p.Deploy.Path)
+}
+
+// fill the --app, --team and --description flags not given by the user
+// with the project file values
+func applyProjectDefaults(cmd *cobra.Command, args []string) {
+	if project == nil {
+		return
+	}
+	setFlagDefault(cmd, "app", project.App)
+	setFlagDefault(cmd, "team", project.Team)
+	if cmd == deployCmd {
+		setFlagDefault(cmd, "description", project.Deploy.Description)
+	}
+}
+
+func setFlagDefault(cmd *cobra.Command, name, value string) {
+	f := cmd.Flags().Lookup(name)
+	if value == "" || f == nil || f.Changed {
+		return
+	}
+	log.WithField("flag", name).WithField("value", value).Debug("Using value from the project file")
+	// set the value directly, so the flag isn't marked as changed by the user
+	f.Value.Set(value)
+}
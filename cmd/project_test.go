@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func TestFindProjectFile(t *testing.T) {
+	root, err := ioutil.TempDir("", "teresa")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	sub := filepath.Join(root, "src", "handlers")
+	if err = os.MkdirAll(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if f, err := findProjectFile(sub); err != nil || f != "" {
+		t.Fatalf("expected no project file, got %q (err: %v)", f, err)
+	}
+
+	pf := filepath.Join(root, projectFileName)
+	y := []byte("team: site\napp: webapi\ndeploy:\n  path: src\n")
+	if err = ioutil.WriteFile(pf, y, 0644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := findProjectFile(sub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f != pf {
+		t.Fatalf("expected project file %s, got %s", pf, f)
+	}
+	p, err := readProjectFile(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Team != "site" || p.App != "webapi" {
+		t.Errorf("unexpected project settings %+v", *p)
+	}
+	if p.deployPath() != filepath.Join(root, "src") {
+		t.Errorf("expected deploy path %s, got %s", filepath.Join(root, "src"), p.deployPath())
+	}
+}
+
+func TestProjectPrecedence(t *testing.T) {
+	f, cleanup := newTempConfigFile(t)
+	defer cleanup()
+	if err := ioutil.WriteFile(f, []byte("current_cluster: from-config\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	viper.SetConfigFile(f)
+	if err := viper.ReadInConfig(); err != nil {
+		t.Fatal(err)
+	}
+	clusterFlag := RootCmd.PersistentFlags().Lookup("cluster")
+	defer func() {
+		ioutil.WriteFile(f, nil, 0600)
+		viper.ReadInConfig()
+		clusterFlag.Value.Set("")
+		clusterFlag.Changed = false
+		os.Unsetenv("TERESA_CLUSTER")
+		project = nil
+	}()
+	viper.AutomaticEnv()
+
+	// each step sets a source with higher precedence than the previous
+	var steps = []struct {
+		set      func()
+		expected string
+	}{
+		{func() {}, "from-config"},
+		{func() { project = &projectConfig{Cluster: "from-project", Team: "site", App: "webapi"} }, "from-project"},
+		{func() { os.Setenv("TERESA_CLUSTER", "from-env") }, "from-env"},
+		{func() { RootCmd.PersistentFlags().Set("cluster", "from-flag") }, "from-flag"},
+	}
+	for _, s := range steps {
+		s.set()
+		if n, err := getCurrentClusterName(); err != nil || n != s.expected {
+			t.Errorf("expected the cluster %s, got %q (err: %v)", s.expected, n, err)
+		}
+	}
+
+	cmd := &cobra.Command{}
+	cmd.Flags().StringVar(new(string), "team", "", "")
+	cmd.Flags().StringVar(new(string), "app", "", "")
+	if err := cmd.Flags().Set("app", "from-flag"); err != nil {
+		t.Fatal(err)
+	}
+	applyProjectDefaults(cmd, nil)
+	if team := cmd.Flags().Lookup("team"); team.Value.String() != "site" || team.Changed {
+		t.Errorf("expected the team from the project, not marked as set by the user, got %+v", team)
+	}
+	if app := cmd.Flags().Lookup("app").Value.String(); app != "from-flag" {
+		t.Errorf("expected the app flag to take precedence over the project, got %s", app)
+	}
+}
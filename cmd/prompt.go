@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/howeyc/gopass"
+)
+
+// read a line from the user, without the trailing newline
+func readLine(prompt string) (string, error) {
+	fmt.Print(prompt)
+	s, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(s, "\r\n"), nil
+}
+
+// ask the user a yes/no question, defaulting to no
+func confirm(question string) bool {
+	a, err := readLine(fmt.Sprintf("%s [y/N]: ", question))
+	if err != nil {
+		return false
+	}
+	a = strings.ToLower(strings.TrimSpace(a))
+	return a == "y" || a == "yes"
+}
+
+// read a password from the user, echoing asterisks
+func readPassword(prompt string) (string, error) {
+	fmt.Print(prompt)
+	p, err := gopass.GetPasswdMasked()
+	if err != nil {
+		return "", err
+	}
+	return string(p), nil
+}
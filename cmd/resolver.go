@@ -0,0 +1,268 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/luizalabs/teresa-api/models"
+	"gopkg.in/yaml.v2"
+)
+
+// how long the team and app IDs are cached
+const resolverCacheTTL = 10 * time.Minute
+
+type resolverCacheEntry struct {
+	ID int64 `yaml:"id"`
+	// team of the app, for the app entries
+	TeamID   int64     `yaml:"team_id,omitempty"`
+	CachedAt time.Time `yaml:"cached_at"`
+}
+
+// name to ID mappings of a cluster, saved on disk
+type resolverCache struct {
+	Teams map[string]resolverCacheEntry `yaml:"teams"`
+	// keyed by "team/app"
+	Apps map[string]resolverCacheEntry `yaml:"apps"`
+}
+
+// ResolveTeamID returns the ID of the team. When no name is given, the user
+// must be member of a single team
+func (tc TeresaClient) ResolveTeamID(teamName string) (int64, error) {
+	if teamName == "" {
+		teams, err := tc.myTeams()
+		if err != nil {
+			return 0, err
+		}
+		switch len(teams) {
+		case 0:
+			return 0, newSysError("You are not member of any team")
+		case 1:
+			return teams[0].ID, nil
+		}
+		names := make(map[string]int64, len(teams))
+		for _, t := range teams {
+			names[*t.Name] = t.ID
+		}
+		return 0, ambiguousTeamError("You are member of more than one team", names)
+	}
+
+	f := tc.resolverCacheFile()
+	c := loadResolverCache(f)
+	if e, ok := c.Teams[teamName]; ok && time.Since(e.CachedAt) < resolverCacheTTL {
+		log.WithField("team", teamName).Debug("Team ID found on cache")
+		return e.ID, nil
+	}
+	teams, err := tc.GetTeams()
+	if err != nil {
+		return 0, fmt.Errorf("unable to get the teams: %s", err)
+	}
+	var id int64
+	now := time.Now()
+	for _, t := range teams {
+		c.Teams[*t.Name] = resolverCacheEntry{ID: t.ID, CachedAt: now}
+		if *t.Name == teamName {
+			id = t.ID
+		}
+	}
+	c.save(f)
+	if id == 0 {
+		return 0, newSysError(fmt.Sprintf("Invalid Team [%s]", teamName))
+	}
+	return id, nil
+}
+
+// ResolveAppInfo returns the IDs of the app and its team. When no team is
+// given, the app must exist in a single team of the user
+func (tc TeresaClient) ResolveAppInfo(teamName, appName string) (AppInfo, error) {
+	if teamName != "" {
+		teamID, err := tc.ResolveTeamID(teamName)
+		if err != nil {
+			return AppInfo{}, err
+		}
+		appID, err := tc.resolveAppID(teamName, teamID, appName)
+		if err != nil {
+			return AppInfo{}, err
+		}
+		return AppInfo{TeamID: teamID, AppID: appID}, nil
+	}
+
+	teams, err := tc.myTeams()
+	if err != nil {
+		return AppInfo{}, err
+	}
+	found := make(map[string]int64)
+	var info AppInfo
+	for _, t := range teams {
+		for _, a := range t.Apps {
+			if *a.Name == appName {
+				found[*t.Name] = t.ID
+				info = AppInfo{TeamID: t.ID, AppID: a.ID}
+			}
+		}
+	}
+	switch len(found) {
+	case 0:
+		return AppInfo{}, newSysError(fmt.Sprintf("Invalid App [%s], not found in your teams", appName))
+	case 1:
+		return info, nil
+	}
+	return AppInfo{}, ambiguousTeamError(fmt.Sprintf("The app %s exists in more than one of your teams", appName), found)
+}
+
+func (tc TeresaClient) resolveAppID(teamName string, teamID int64, appName string) (int64, error) {
+	f := tc.resolverCacheFile()
+	c := loadResolverCache(f)
+	k := teamName + "/" + appName
+	if e, ok := c.Apps[k]; ok && e.TeamID == teamID && time.Since(e.CachedAt) < resolverCacheTTL {
+		log.WithField("app", k).Debug("App ID found on cache")
+		return e.ID, nil
+	}
+	apps, err := tc.GetApps(teamID)
+	if err != nil {
+		return 0, fmt.Errorf("unable to get the apps of team %s: %s", teamName, err)
+	}
+	var id int64
+	now := time.Now()
+	for _, a := range apps {
+		c.Apps[teamName+"/"+*a.Name] = resolverCacheEntry{ID: a.ID, TeamID: teamID, CachedAt: now}
+		if *a.Name == appName {
+			id = a.ID
+		}
+	}
+	c.save(f)
+	if id == 0 {
+		return 0, newSysError(fmt.Sprintf("Invalid Team [%s] or App [%s]", teamName, appName))
+	}
+	return id, nil
+}
+
+// return the teams the user is member of with their apps, in a single
+// request. Their IDs are cached for the lookups by name
+func (tc TeresaClient) myTeams() ([]*models.Team, error) {
+	me, err := tc.Me()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get user information: %s", err)
+	}
+	f := tc.resolverCacheFile()
+	c := loadResolverCache(f)
+	now := time.Now()
+	for _, t := range me.Teams {
+		c.Teams[*t.Name] = resolverCacheEntry{ID: t.ID, CachedAt: now}
+		for _, a := range t.Apps {
+			c.Apps[*t.Name+"/"+*a.Name] = resolverCacheEntry{ID: a.ID, TeamID: t.ID, CachedAt: now}
+		}
+	}
+	c.save(f)
+	return me.Teams, nil
+}
+
+func ambiguousTeamError(msg string, teams map[string]int64) error {
+	names := make([]string, 0, len(teams))
+	for n := range teams {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return newSysError(fmt.Sprintf("%s, provide one with --team: %s", msg, strings.Join(names, ", ")))
+}
+
+// remove the team and its apps from the cache
+func (tc TeresaClient) forgetTeam(teamID int64) {
+	f := tc.resolverCacheFile()
+	if f == "" {
+		return
+	}
+	c := loadResolverCache(f)
+	for k, e := range c.Teams {
+		if e.ID == teamID {
+			delete(c.Teams, k)
+		}
+	}
+	for k, e := range c.Apps {
+		if e.TeamID == teamID {
+			delete(c.Apps, k)
+		}
+	}
+	c.save(f)
+}
+
+// remove the app from the cache
+func (tc TeresaClient) forgetApp(teamID, appID int64) {
+	f := tc.resolverCacheFile()
+	if f == "" {
+		return
+	}
+	c := loadResolverCache(f)
+	for k, e := range c.Apps {
+		if e.TeamID == teamID && e.ID == appID {
+			delete(c.Apps, k)
+		}
+	}
+	c.save(f)
+}
+
+// the cache file of the cluster, next to the config file. Empty when
+// there is no cluster to cache for
+func (tc TeresaClient) resolverCacheFile() string {
+	if tc.cluster == "" || cfgFile == "" {
+		return ""
+	}
+	return clusterCacheFile(cfgFile, tc.cluster, ".yaml")
+}
+
+// the cache files of the clusters live in a cache dir next to the config
+// file, one per kind of data
+var clusterCacheSuffixes = []string{".yaml", ".names.yaml", ".version.yaml"}
+
+func clusterCacheFile(cfg, cluster, suffix string) string {
+	return filepath.Join(filepath.Dir(cfg), "cache", url.QueryEscape(cluster)+suffix)
+}
+
+// remove the cached data of the cluster, which may belong to another server
+// after the cluster is changed, removed or renamed
+func clearClusterCache(cfg, cluster string) {
+	for _, s := range clusterCacheSuffixes {
+		f := clusterCacheFile(cfg, cluster, s)
+		if err := os.Remove(f); err != nil && !os.IsNotExist(err) {
+			log.WithError(err).WithField("cacheFile", f).Debug("Failed to remove the cache file")
+		}
+	}
+}
+
+// load the cache from disk. Errors are only logged, as the cache is optional
+func loadResolverCache(f string) *resolverCache {
+	c := &resolverCache{}
+	if f != "" {
+		if y, err := ioutil.ReadFile(f); err == nil {
+			if err = yaml.Unmarshal(y, c); err != nil {
+				log.WithError(err).WithField("cacheFile", f).Debug("Ignoring invalid cache file")
+				c = &resolverCache{}
+			}
+		}
+	}
+	if c.Teams == nil {
+		c.Teams = make(map[string]resolverCacheEntry)
+	}
+	if c.Apps == nil {
+		c.Apps = make(map[string]resolverCacheEntry)
+	}
+	return c
+}
+
+func (c *resolverCache) save(f string) {
+	if f == "" {
+		return
+	}
+	y, err := yaml.Marshal(c)
+	if err == nil {
+		err = writeFileAtomic(f, y)
+	}
+	if err != nil {
+		log.WithError(err).WithField("cacheFile", f).Debug("Failed to save the cache file")
+	}
+}
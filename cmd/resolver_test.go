@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func newResolverTestAPI() *fakeAPI {
+	return &fakeAPI{responses: map[string]string{
+		"/v1/users/me":     `{"email": "gopher@luizalabs.com", "teams": [{"id": 1, "name": "site", "apps": [{"id": 10, "name": "webapi"}]}, {"id": 2, "name": "checkout", "apps": [{"id": 20, "name": "webapi"}, {"id": 21, "name": "worker"}]}]}`,
+		"/v1/teams":        `{"items": [{"id": 1, "name": "site", "iAmMember": true}, {"id": 2, "name": "checkout", "iAmMember": true}, {"id": 3, "name": "other"}]}`,
+		"/v1/teams/1/apps": `{"items": [{"id": 10, "name": "webapi"}]}`,
+		"/v1/teams/2/apps": `{"items": [{"id": 20, "name": "webapi"}, {"id": 21, "name": "worker"}]}`,
+		"/v1/teams/3/apps": `{"items": []}`,
+	}}
+}
+
+func TestResolveAppInfo(t *testing.T) {
+	tc, ts := newTestClient(t, newResolverTestAPI())
+	defer ts.Close()
+
+	a, err := tc.ResolveAppInfo("", "worker")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.TeamID != 2 || a.AppID != 21 {
+		t.Errorf("expected team 2 and app 21, got %+v", a)
+	}
+	if a, err = tc.ResolveAppInfo("site", "webapi"); err != nil || a.TeamID != 1 || a.AppID != 10 {
+		t.Errorf("expected team 1 and app 10, got %+v (err: %v)", a, err)
+	}
+
+	_, err = tc.ResolveAppInfo("", "webapi")
+	if err == nil || !strings.Contains(err.Error(), "checkout, site") {
+		t.Errorf("expected an ambiguity error listing the teams, got %v", err)
+	}
+	_, err = tc.ResolveTeamID("")
+	if err == nil || !strings.Contains(err.Error(), "checkout, site") {
+		t.Errorf("expected an ambiguity error listing the teams, got %v", err)
+	}
+	if _, err = tc.ResolveAppInfo("other", "webapi"); err == nil {
+		t.Error("expected an error resolving an app from another team")
+	}
+}
+
+func TestResolverCache(t *testing.T) {
+	f, cleanup := newTempConfigFile(t)
+	defer cleanup()
+	defer func(old string) { cfgFile = old }(cfgFile)
+	cfgFile = f
+
+	api := newResolverTestAPI()
+	tc, ts := newTestClient(t, api)
+	defer ts.Close()
+	tc.cluster = "test"
+
+	if _, err := tc.ResolveAppInfo("checkout", "worker"); err != nil {
+		t.Fatal(err)
+	}
+	api.requests = nil
+	a, err := tc.ResolveAppInfo("checkout", "worker")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(api.requests) != 0 || a.AppID != 21 {
+		t.Errorf("expected app 21 from the cache, got %+v with requests %v", a, api.requests)
+	}
+
+	tc.forgetApp(2, 21)
+	if _, err = tc.ResolveAppInfo("checkout", "worker"); err != nil {
+		t.Fatal(err)
+	}
+	if len(api.requests) == 0 {
+		t.Error("expected the forgotten app to be fetched again")
+	}
+}
+
+func TestResolveAppInfoWithoutTeam(t *testing.T) {
+	f, cleanup := newTempConfigFile(t)
+	defer cleanup()
+	defer func(old string) { cfgFile = old }(cfgFile)
+	cfgFile = f
+
+	api := newResolverTestAPI()
+	tc, ts := newTestClient(t, api)
+	defer ts.Close()
+	tc.cluster = "test"
+
+	if _, err := tc.ResolveAppInfo("", "worker"); err != nil {
+		t.Fatal(err)
+	}
+	if len(api.requests) != 1 || !strings.HasPrefix(api.requests[0], "GET /v1/users/me") {
+		t.Errorf("expected a single request of the user, got %v", api.requests)
+	}
+	// the apps of the user teams are cached for the lookups by name
+	api.requests = nil
+	if a, err := tc.ResolveAppInfo("site", "webapi"); err != nil || a.AppID != 10 {
+		t.Errorf("expected app 10, got %+v (err: %v)", a, err)
+	}
+	if len(api.requests) != 0 {
+		t.Errorf("expected the app from the cache, got requests %v", api.requests)
+	}
+}
+
+func TestClearClusterCache(t *testing.T) {
+	f, cleanup := newTempConfigFile(t)
+	defer cleanup()
+
+	write := func(cluster string) {
+		for _, s := range clusterCacheSuffixes {
+			if err := writeFileAtomic(clusterCacheFile(f, cluster, s), []byte("{}")); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	cleared := func(cluster string) bool {
+		for _, s := range clusterCacheSuffixes {
+			if _, err := os.Stat(clusterCacheFile(f, cluster, s)); !os.IsNotExist(err) {
+				return false
+			}
+		}
+		return true
+	}
+
+	write("prod")
+	if err := setCluster("prod", clusterConfig{Server: "http://localhost"}, true, f); err != nil {
+		t.Fatal(err)
+	}
+	if !cleared("prod") {
+		t.Error("expected set-cluster to clear the cache of the cluster")
+	}
+
+	write("prod")
+	write("staging")
+	if err := renameCluster("prod", "staging", f); err != nil {
+		t.Fatal(err)
+	}
+	if !cleared("prod") || !cleared("staging") {
+		t.Error("expected rename-cluster to clear the cache of both names")
+	}
+
+	write("staging")
+	if err := deleteCluster("staging", f); err != nil {
+		t.Fatal(err)
+	}
+	if !cleared("staging") {
+		t.Error("expected delete-cluster to clear the cache of the cluster")
+	}
+}
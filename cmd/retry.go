@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-openapi/runtime"
+)
+
+const (
+	// longest wait between two attempts, unless the server asks for more
+	maxRetryBackoff = 30 * time.Second
+	// longest Retry-After honoured
+	maxRetryAfter = 2 * time.Minute
+)
+
+// methods safe to send again when the first attempt fails. POSTs, like
+// the deploy upload, are never retried
+var idempotentMethods = map[string]bool{
+	"GET":     true,
+	"HEAD":    true,
+	"OPTIONS": true,
+	"PUT":     true,
+	"DELETE":  true,
+}
+
+// status codes of transient failures, usually from the ingress or load balancer
+var retryableStatusCodes = map[int]bool{
+	http.StatusTooManyRequests:    true,
+	http.StatusBadGateway:         true,
+	http.StatusServiceUnavailable: true,
+	http.StatusGatewayTimeout:     true,
+}
+
+// retryTransport retries the idempotent operations that fail with network
+// errors or transient status codes, with exponential backoff and jitter
+type retryTransport struct {
+	next runtime.ClientTransport
+	// total number of attempts, including the first one
+	attempts int
+	// wait before the first retry, doubled on each attempt
+	backoff time.Duration
+	sleep   func(time.Duration)
+}
+
+func newRetryTransport(next runtime.ClientTransport, attempts int, backoff time.Duration) *retryTransport {
+	return &retryTransport{next: next, attempts: attempts, backoff: backoff, sleep: time.Sleep}
+}
+
+// Submit sends the operation, retrying it when possible
+func (t *retryTransport) Submit(op *runtime.ClientOperation) (interface{}, error) {
+	if t.attempts <= 1 || !idempotentMethods[op.Method] {
+		return t.next.Submit(op)
+	}
+	for attempt := 1; ; attempt++ {
+		// peek the response status and headers before the operation reader
+		status, retryAfter := 0, ""
+		o := *op
+		o.Reader = runtime.ClientResponseReaderFunc(func(r runtime.ClientResponse, c runtime.Consumer) (interface{}, error) {
+			status, retryAfter = r.Code(), r.GetHeader("Retry-After")
+			return op.Reader.ReadResponse(r, c)
+		})
+		res, err := t.next.Submit(&o)
+		if err == nil || attempt >= t.attempts || !isRetryable(err, status) {
+			return res, err
+		}
+		wait := t.wait(attempt, retryAfter)
+		log.WithError(err).WithField("operation", op.ID).Debugf("Retrying in %s (attempt %d of %d)", wait, attempt+1, t.attempts)
+		t.sleep(wait)
+	}
+}
+
+func isRetryable(err error, status int) bool {
+	if status == 0 {
+		// no response, only network errors are worth another try
+		_, ok := err.(net.Error)
+		return ok
+	}
+	return retryableStatusCodes[status]
+}
+
+// time to wait before the next attempt: the Retry-After header, when the
+// server sends one, or the exponential backoff with jitter
+func (t *retryTransport) wait(attempt int, retryAfter string) time.Duration {
+	if d, ok := parseRetryAfter(retryAfter); ok {
+		if d > maxRetryAfter {
+			return maxRetryAfter
+		}
+		return d
+	}
+	d := t.backoff << uint(attempt-1)
+	if d > maxRetryBackoff || d <= 0 {
+		d = maxRetryBackoff
+	}
+	// half fixed, half random, so parallel clients don't retry in lockstep
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// Retry-After is either a number of seconds or a http date
+func parseRetryAfter(v string) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if s, err := strconv.Atoi(v); err == nil && s >= 0 {
+		return time.Duration(s) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		d := t.Sub(time.Now())
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-openapi/runtime"
+	"github.com/spf13/viper"
+)
+
+type fakeResponse struct {
+	code       int
+	retryAfter string
+}
+
+func (r fakeResponse) Code() int       { return r.code }
+func (r fakeResponse) Message() string { return "" }
+func (r fakeResponse) GetHeader(h string) string {
+	if h == "Retry-After" {
+		return r.retryAfter
+	}
+	return ""
+}
+func (r fakeResponse) Body() io.ReadCloser { return ioutil.NopCloser(strings.NewReader("")) }
+
+// answers with the responses in order, the last one repeatedly
+type fakeTransport struct {
+	responses []fakeResponse
+	calls     int
+}
+
+func (t *fakeTransport) Submit(op *runtime.ClientOperation) (interface{}, error) {
+	r := t.responses[len(t.responses)-1]
+	if t.calls < len(t.responses) {
+		r = t.responses[t.calls]
+	}
+	t.calls++
+	return op.Reader.ReadResponse(r, runtime.JSONConsumer())
+}
+
+var statusReader = runtime.ClientResponseReaderFunc(func(r runtime.ClientResponse, c runtime.Consumer) (interface{}, error) {
+	if r.Code() >= 400 {
+		return nil, errors.New("failed")
+	}
+	return r.Code(), nil
+})
+
+func newTestRetryTransport(next runtime.ClientTransport, waits *[]time.Duration) *retryTransport {
+	t := newRetryTransport(next, 3, 100*time.Millisecond)
+	t.sleep = func(d time.Duration) { *waits = append(*waits, d) }
+	return t
+}
+
+func TestRetryTransportRetriesIdempotentCalls(t *testing.T) {
+	var waits []time.Duration
+	ft := &fakeTransport{responses: []fakeResponse{{code: 502}, {code: 503, retryAfter: "7"}, {code: 200}}}
+	res, err := newTestRetryTransport(ft, &waits).Submit(&runtime.ClientOperation{ID: "get", Method: "GET", Reader: statusReader})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res != 200 || ft.calls != 3 {
+		t.Errorf("expected success on the 3rd attempt, got %v after %d calls", res, ft.calls)
+	}
+	if len(waits) != 2 || waits[0] < 50*time.Millisecond || waits[0] > 100*time.Millisecond {
+		t.Errorf("expected a backoff between 50ms and 100ms, got %v", waits)
+	}
+	if waits[1] != 7*time.Second {
+		t.Errorf("expected the Retry-After to be honoured, got %v", waits[1])
+	}
+}
+
+func TestRetryTransportGivesUp(t *testing.T) {
+	var waits []time.Duration
+	ft := &fakeTransport{responses: []fakeResponse{{code: 502}}}
+	if _, err := newTestRetryTransport(ft, &waits).Submit(&runtime.ClientOperation{ID: "get", Method: "GET", Reader: statusReader}); err == nil {
+		t.Error("expected an error after all the attempts")
+	}
+	if ft.calls != 3 {
+		t.Errorf("expected 3 attempts, got %d", ft.calls)
+	}
+
+	ft = &fakeTransport{responses: []fakeResponse{{code: 404}}}
+	newTestRetryTransport(ft, &waits).Submit(&runtime.ClientOperation{ID: "get", Method: "GET", Reader: statusReader})
+	if ft.calls != 1 {
+		t.Errorf("expected no retries for a 404, got %d attempts", ft.calls)
+	}
+}
+
+func TestRetryTransportDoesNotRetryPosts(t *testing.T) {
+	var waits []time.Duration
+	ft := &fakeTransport{responses: []fakeResponse{{code: 502}, {code: 200}}}
+	if _, err := newTestRetryTransport(ft, &waits).Submit(&runtime.ClientOperation{ID: "createDeployment", Method: "POST", Reader: statusReader}); err == nil {
+		t.Error("expected the POST error to be returned")
+	}
+	if ft.calls != 1 {
+		t.Errorf("expected a single attempt for a POST, got %d", ft.calls)
+	}
+}
+
+func TestRetryHTMLBadGateway(t *testing.T) {
+	defer func(r, b interface{}) { viper.Set("retries", r); viper.Set("retry_backoff", b) }(viper.Get("retries"), viper.Get("retry_backoff"))
+	viper.Set("retries", 3)
+	viper.Set("retry_backoff", "1ms")
+
+	// the ingress answers the first call with its html error page
+	calls := 0
+	api := newMeTestAPI()
+	tc, ts := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.Header().Set("Content-Type", "text/html")
+			w.WriteHeader(http.StatusBadGateway)
+			w.Write([]byte("<html><body>502 Bad Gateway</body></html>"))
+			return
+		}
+		api.ServeHTTP(w, r)
+	}))
+	defer ts.Close()
+
+	if _, err := tc.Me(); err != nil {
+		t.Fatalf("expected the html 502 to be retried, got %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("expected 2 calls, got %d", calls)
+	}
+}
@@ -42,6 +42,22 @@ From that point on, all the operations will be directed to that cluster. You can
 view the whole configuration anytime by running:
 
   $ teresa config view
+
+To send a single command to another cluster, without changing the current one,
+use the --cluster flag or the TERESA_CLUSTER environment variable:
+
+  $ teresa deploy . --app webapi --cluster my_other_cluster
+  $ TERESA_CLUSTER=my_other_cluster teresa get app --app webapi
+
+Inside a project, the cluster, team and app can be set on a .teresa.yaml file,
+looked up from the current directory upwards. Flags always take precedence:
+
+  cluster: my_cluster_name
+  team: site
+  app: webapi
+  deploy:
+    path: .
+    description: deployed from the project folder
 	`,
 }
 
@@ -65,13 +81,22 @@ func Execute() {
 }
 
 func init() {
-	cobra.OnInitialize(initLog, initConfig)
+	cobra.OnInitialize(initLog, initConfig, initProject)
+	RootCmd.PersistentPreRun = applyProjectDefaults
 	// using this so i will check manualy for strange behavior of the cli
 	RootCmd.SilenceErrors = true
 	RootCmd.SilenceUsage = true
 	// change the suggestion distance of the commands
 	RootCmd.SuggestionsMinimumDistance = 3
 	RootCmd.PersistentFlags().StringVarP(&cfgFile, "config", "c", "", "config file")
+	RootCmd.PersistentFlags().StringVar(&clusterFlag, "cluster", "", "cluster to use instead of the current one")
+	// the key is named after the env var, so the automatic env lookup
+	// doesn't pick a generic CLUSTER var
+	viper.BindPFlag("teresa_cluster", RootCmd.PersistentFlags().Lookup("cluster"))
+	viper.BindEnv("teresa_cluster", "TERESA_CLUSTER")
+	viper.BindEnv("retries", "TERESA_RETRIES")
+	viper.BindEnv("retry_backoff", "TERESA_RETRY_BACKOFF")
+	viper.BindEnv("update_url", "TERESA_UPDATE_URL")
 }
 
 func initLog() {
@@ -104,9 +129,17 @@ func initConfig() {
 	} else {
 		cfgFile = filepath.Join(getUserHomeDir(), ".teresa", "config.yaml")
 	}
+	if err := migrateConfigFile(cfgFile); err != nil {
+		log.Fatal(err)
+	}
 	viper.SetConfigFile(cfgFile)
 	// defaults
 	viper.SetDefault("debug", false)
+	// attempts of the idempotent api calls, see retryTransport
+	viper.SetDefault("retries", 3)
+	viper.SetDefault("retry_backoff", "500ms")
+	// release index of the update command, set at build time
+	viper.SetDefault("update_url", updateIndexURL)
 	// get from ENV
 	viper.AutomaticEnv()
 	if err := viper.ReadInConfig(); err != nil && !os.IsNotExist(err) {
@@ -125,7 +158,15 @@ func initConfig() {
 func Fatalf(cmd *cobra.Command, format string, a ...interface{}) {
 	s := fmt.Sprintf(format, a...)
 	s = fmt.Sprintf("%s\n%s\n\n%s", s, cmd.Long, cmd.UsageString())
-	log.Fatalf(s)
+	log.Fatal(s)
+}
+
+// yesNo formats a boolean for the tables
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
 }
 
 // Usage Prints the cmd Long description and the usage string
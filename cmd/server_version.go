@@ -0,0 +1,166 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-openapi/runtime"
+	"github.com/go-openapi/strfmt"
+	"gopkg.in/yaml.v2"
+)
+
+// how long the server version is cached
+const serverVersionCacheTTL = time.Hour
+
+// serverVersion is the answer of the server version endpoint
+type serverVersion struct {
+	Version string `json:"version" yaml:"version"`
+	// versions of the api the server answers to, like v1
+	APIVersions []string `json:"api_versions" yaml:"api_versions"`
+	// range of cli versions the server supports, both optional
+	MinClientVersion string `json:"min_client_version" yaml:"min_client_version,omitempty"`
+	MaxClientVersion string `json:"max_client_version" yaml:"max_client_version,omitempty"`
+	// true for servers older than the version endpoint
+	Unknown   bool      `json:"-" yaml:"unknown,omitempty"`
+	CheckedAt time.Time `json:"-" yaml:"checked_at"`
+}
+
+// ServerVersion returns the version of the server, from the cache when
+// checked recently
+func (tc TeresaClient) ServerVersion() (*serverVersion, error) {
+	f := tc.serverVersionCacheFile()
+	if v := loadServerVersion(f); v != nil && time.Since(v.CheckedAt) < serverVersionCacheTTL {
+		log.WithField("cluster", tc.cluster).Debug("Server version found on cache")
+		return v, nil
+	}
+	return tc.refreshServerVersion()
+}
+
+// get the server version from the server, updating the cache
+func (tc TeresaClient) refreshServerVersion() (*serverVersion, error) {
+	v := &serverVersion{}
+	params := func(r runtime.ClientRequest, reg strfmt.Registry) error { return nil }
+	// the version endpoint is the same for every api version, so it is
+	// served at the base path of the server
+	if err := tc.submitTo(tc.root, "getVersion", "GET", "/version", params, v); err != nil {
+		e, ok := err.(*apiError)
+		if !ok || e.Code() != 404 {
+			return nil, err
+		}
+		log.Debug("Server without the version endpoint")
+		v = &serverVersion{Unknown: true}
+	}
+	v.CheckedAt = time.Now()
+	if f := tc.serverVersionCacheFile(); f != "" {
+		y, err := yaml.Marshal(v)
+		if err == nil {
+			err = writeFileAtomic(f, y)
+		}
+		if err != nil {
+			log.WithError(err).WithField("cacheFile", f).Debug("Failed to save the cache file")
+		}
+	}
+	return v, nil
+}
+
+// problems of using this cli with the server, empty when compatible
+func (v *serverVersion) compatibilityWarnings(apiVersion string) (w []string) {
+	if v.Unknown {
+		return
+	}
+	if v.MinClientVersion != "" && compareVersions(version, v.MinClientVersion) < 0 {
+		w = append(w, fmt.Sprintf("This cli (%s) is older than the oldest supported by the server (%s), please upgrade it", version, v.MinClientVersion))
+	}
+	if v.MaxClientVersion != "" && compareVersions(version, v.MaxClientVersion) > 0 {
+		w = append(w, fmt.Sprintf("This cli (%s) is newer than the newest supported by the server (%s)", version, v.MaxClientVersion))
+	}
+	if len(v.APIVersions) > 0 && !v.supportsAPI(apiVersion) {
+		w = append(w, fmt.Sprintf("The api version in use (%s) isn't supported by the server (%s)", apiVersion, strings.Join(v.APIVersions, ", ")))
+	}
+	return
+}
+
+func (v *serverVersion) supportsAPI(apiVersion string) bool {
+	for _, a := range v.APIVersions {
+		if a == apiVersion {
+			return true
+		}
+	}
+	return false
+}
+
+// the version cache file of the cluster, next to the resolver one
+func (tc TeresaClient) serverVersionCacheFile() string {
+	if tc.cluster == "" || cfgFile == "" {
+		return ""
+	}
+	return clusterCacheFile(cfgFile, tc.cluster, ".version.yaml")
+}
+
+// load the cached server version, nil if there is none
+func loadServerVersion(f string) *serverVersion {
+	if f == "" {
+		return nil
+	}
+	y, err := ioutil.ReadFile(f)
+	if err != nil {
+		return nil
+	}
+	v := &serverVersion{}
+	if err = yaml.Unmarshal(y, v); err != nil {
+		log.WithError(err).WithField("cacheFile", f).Debug("Ignoring invalid cache file")
+		return nil
+	}
+	return v
+}
+
+// compare two versions like 1.2.3, with or without a "v" prefix. A
+// pre-release (1.2.3-rc1) is older than the release. Returns -1, 0 or 1
+func compareVersions(a, b string) int {
+	pa, prea := splitVersion(a)
+	pb, preb := splitVersion(b)
+	for i := 0; i < len(pa) || i < len(pb); i++ {
+		var x, y int
+		if i < len(pa) {
+			x = pa[i]
+		}
+		if i < len(pb) {
+			y = pb[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	switch {
+	case prea == preb:
+		return 0
+	case prea == "":
+		return 1
+	case preb == "":
+		return -1
+	case prea < preb:
+		return -1
+	}
+	return 1
+}
+
+func splitVersion(v string) (parts []int, pre string) {
+	v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+	if i := strings.IndexAny(v, "-+"); i >= 0 {
+		if v[i] == '-' {
+			pre = v[i+1:]
+		}
+		v = v[:i]
+	}
+	for _, p := range strings.Split(v, ".") {
+		n, _ := strconv.Atoi(p)
+		parts = append(parts, n)
+	}
+	return
+}
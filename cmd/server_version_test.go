@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCompareVersions(t *testing.T) {
+	var tests = []struct {
+		a, b     string
+		expected int
+	}{
+		{"0.1.2", "0.1.2", 0},
+		{"v0.1.2", "0.1.2", 0},
+		{"0.1", "0.1.0", 0},
+		{"0.1.2", "0.1.10", -1},
+		{"1.0.0", "0.9.9", 1},
+		{"1.0.0-rc1", "1.0.0", -1},
+		{"1.0.0-rc2", "1.0.0-rc1", 1},
+	}
+	for _, tt := range tests {
+		if got := compareVersions(tt.a, tt.b); got != tt.expected {
+			t.Errorf("compareVersions(%q, %q): expected %d, got %d", tt.a, tt.b, tt.expected, got)
+		}
+	}
+}
+
+func TestServerVersion(t *testing.T) {
+	f, cleanup := newTempConfigFile(t)
+	defer cleanup()
+	defer func(old string) { cfgFile = old }(cfgFile)
+	cfgFile = f
+
+	api := &fakeAPI{responses: map[string]string{
+		"/version": `{"version": "0.3.0", "api_versions": ["v1", "v2"], "min_client_version": "9.0.0"}`,
+	}}
+	tc, ts := newTestClient(t, api)
+	defer ts.Close()
+	tc.cluster = "test"
+
+	for i := 0; i < 2; i++ {
+		v, err := tc.ServerVersion()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v.Version != "0.3.0" || len(v.APIVersions) != 2 {
+			t.Errorf("unexpected server version %+v", v)
+		}
+	}
+	if len(api.requests) != 1 {
+		t.Errorf("expected the version to be cached, got requests %v", api.requests)
+	}
+	if len(api.requests) != 1 || api.requests[0] != "GET /version" {
+		t.Errorf("expected the version at the base path, got requests %v", api.requests)
+	}
+	v, _ := tc.ServerVersion()
+	if w := v.compatibilityWarnings("v1"); len(w) != 1 {
+		t.Errorf("expected a warning about the old cli, got %v", w)
+	}
+	if w := v.compatibilityWarnings("v3"); len(w) != 2 {
+		t.Errorf("expected warnings about the old cli and the api version, got %v", w)
+	}
+}
+
+func TestServerVersionUnknown(t *testing.T) {
+	tc, ts := newTestClient(t, http.NotFoundHandler())
+	defer ts.Close()
+	v, err := tc.ServerVersion()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !v.Unknown || len(v.compatibilityWarnings("v1")) != 0 {
+		t.Errorf("expected an unknown version without warnings, got %+v", v)
+	}
+}
+
+func TestServerVersionPathPrefix(t *testing.T) {
+	api := &fakeAPI{responses: map[string]string{"/teresa/version": `{"version": "0.3.0"}`}}
+	ts := httptest.NewServer(api)
+	defer ts.Close()
+	tc, err := newTeresaClient(clusterConfig{Server: ts.URL + "/teresa/", APIVersion: "v2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, err := tc.ServerVersion(); err != nil || v.Version != "0.3.0" {
+		t.Errorf("expected the version under the path prefix, got %+v (err: %v) with requests %v", v, err, api.requests)
+	}
+}
+
+func TestServerVersionGatewayNotFound(t *testing.T) {
+	tc, ts := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("<html><body>404 Not Found</body></html>"))
+	}))
+	defer ts.Close()
+	v, err := tc.ServerVersion()
+	if err != nil || !v.Unknown {
+		t.Errorf("expected an unknown version from the html 404, got %+v (err: %v)", v, err)
+	}
+}
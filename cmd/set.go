@@ -4,7 +4,7 @@ import "github.com/spf13/cobra"
 
 var setCmd = &cobra.Command{
 	Use:   "set",
-	Short: "Set properties for an app.",
+	Short: "Set properties for an app, team or user.",
 }
 
 func init() {
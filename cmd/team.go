@@ -2,7 +2,15 @@ package cmd
 
 import (
 	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/go-openapi/strfmt"
+	"github.com/luizalabs/teresa-api/client/teams"
+	"github.com/luizalabs/teresa-api/models"
+	"github.com/olekukonko/tablewriter"
 	_ "github.com/prometheus/common/log"
 	"github.com/spf13/cobra"
 )
@@ -33,20 +41,226 @@ eg.:
 
 // delete team
 var deleteTeamCmd = &cobra.Command{
-	Use:   "team",
+	Use:   "team [name]",
 	Short: "Delete a team",
-	Long:  `Delete a team`,
+	Long: `Delete a team.
+
+The team to delete is shown and must be confirmed before deleting it:
+
+	$ teresa delete team my-team
+
+To skip the confirmation, eg. on scripts:
+
+	$ teresa delete team my-team --yes
+`,
 	Run: func(cmd *cobra.Command, args []string) {
-		if teamIDFlag == 0 {
-			Fatalf(cmd, "team ID is required")
+		if len(args) == 0 && teamIDFlag == 0 {
+			Fatalf(cmd, "team name is required")
+		}
+		tc := NewTeresa()
+		id := teamIDFlag
+		if len(args) > 0 {
+			t, err := tc.GetTeamByName(args[0])
+			if err != nil {
+				log.Fatal(err)
+			}
+			id = t.ID
+		}
+		team, err := tc.GetTeamDetail(id)
+		if err != nil {
+			log.Fatalf("Failed to retrieve the team: %s", err)
 		}
-		if err := NewTeresa().DeleteTeam(teamIDFlag); err != nil {
+		if !yesFlag {
+			fmt.Printf("Team %s will be deleted, along with its %d member(s) and %d app(s):\n", *team.Name, len(team.Members), len(team.Apps))
+			for _, a := range team.Apps {
+				fmt.Printf("  - %s\n", *a.Name)
+			}
+			if !confirm("Are you sure?") {
+				log.Fatal("Aborted, nothing was deleted")
+			}
+		}
+		if err := tc.DeleteTeam(id); err != nil {
 			log.Fatalf("Failed to delete team: %s", err)
 		}
 		log.Infof("Team deleted.")
 	},
 }
 
+var setTeamCmd = &cobra.Command{
+	Use:   "team name",
+	Short: "Set team attributes",
+	Long: `Update the contact email, site URL or name of a team.
+
+eg.:
+
+	$ teresa set team my-team --email sitedev@mydomain.com --url sitedev.mydomain.com
+	$ teresa set team my-team --rename site
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) == 0 {
+			Usage(cmd)
+			return
+		}
+		var changes teamChanges
+		if cmd.Flags().Changed("email") {
+			changes.email = &teamEmailFlag
+		}
+		if cmd.Flags().Changed("url") {
+			changes.url = &teamURLFlag
+		}
+		if teamRenameFlag != "" {
+			changes.name = &teamRenameFlag
+		}
+		if changes.empty() {
+			Fatalf(cmd, "nothing to change, provide --email, --url or --rename")
+		}
+		tc := NewTeresa()
+		before, err := tc.GetTeamByName(args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+		after, err := changes.apply(before)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if after, err = tc.UpdateTeam(after); err != nil {
+			log.Fatalf("Failed to update team: %s", err)
+		}
+		log.Info("Team updated.")
+
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{"", "BEFORE", "AFTER"})
+		table.SetAlignment(tablewriter.ALIGN_LEFT)
+		table.SetAutoWrapText(false)
+		table.Append([]string{"name", *before.Name, *after.Name})
+		table.Append([]string{"email", string(before.Email), string(after.Email)})
+		table.Append([]string{"url", before.URL, after.URL})
+		table.Render()
+	},
+}
+
+// the team attributes given to set team, nil when not given
+type teamChanges struct {
+	email, url, name *string
+}
+
+func (c teamChanges) empty() bool {
+	return c.email == nil && c.url == nil && c.name == nil
+}
+
+// a copy of the team with the changes, validated
+func (c teamChanges) apply(before *models.Team) (*models.Team, error) {
+	after := &models.Team{ID: before.ID, Name: before.Name, Email: before.Email, URL: before.URL}
+	if c.email != nil {
+		// the generated validation doesn't check the email format
+		if *c.email != "" && !strfmt.Default.Validates("email", *c.email) {
+			return nil, newSysError(fmt.Sprintf("Invalid team email: %s", *c.email))
+		}
+		after.Email = strfmt.Email(*c.email)
+	}
+	if c.url != nil {
+		after.URL = *c.url
+	}
+	if c.name != nil {
+		after.Name = c.name
+	}
+	if err := after.Validate(strfmt.Default); err != nil {
+		return nil, newSysError(fmt.Sprintf("Invalid team: %s", err))
+	}
+	return after, nil
+}
+
+var getTeamCmd = &cobra.Command{
+	Use:   "team name",
+	Short: "Get team details",
+	Long: `Return the team members and apps, with the latest deployment of each app.
+
+eg.:
+
+	$ teresa get team my-team
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) == 0 {
+			Usage(cmd)
+			return
+		}
+		tc := NewTeresa()
+		t, err := tc.GetTeamByName(args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+		team, err := tc.GetTeamDetail(t.ID)
+		if err != nil {
+			log.Fatalf("Failed to retrieve the team: %s", err)
+		}
+
+		fmt.Printf("\nTeam: %s\n", *team.Name)
+		if team.Email != "" {
+			fmt.Printf("Contact: %s\n", team.Email)
+		}
+		if team.URL != "" {
+			fmt.Printf("URL: %s\n", team.URL)
+		}
+
+		fmt.Print("\nMembers:\n")
+		members := tablewriter.NewWriter(os.Stdout)
+		members.SetHeader([]string{"NAME", "EMAIL", "ADMIN"})
+		members.SetAlignment(tablewriter.ALIGN_LEFT)
+		members.SetAutoWrapText(false)
+		for _, u := range team.Members {
+			members.Append([]string{*u.Name, *u.Email, yesNo(u.IsAdmin != nil && *u.IsAdmin)})
+		}
+		members.Render()
+
+		fmt.Print("\nApps:\n")
+		apps := tablewriter.NewWriter(os.Stdout)
+		apps.SetHeader([]string{"APP", "SCALE", "ADDRESS", "LATEST DEPLOYMENT"})
+		apps.SetRowLine(true)
+		apps.SetAlignment(tablewriter.ALIGN_LEFT)
+		apps.SetRowSeparator("-")
+		apps.SetAutoWrapText(false)
+		for _, a := range team.Apps {
+			l := a.DeploymentList
+			if len(l) == 0 {
+				if l, err = tc.GetDeployments(team.ID, a.ID); err != nil {
+					log.WithError(err).WithField("app", *a.Name).Warn("Failed to retrieve the app deployments")
+				}
+			}
+			scale := ""
+			if a.Scale != nil {
+				scale = strconv.Itoa(int(*a.Scale))
+			}
+			apps.Append([]string{*a.Name, scale, strings.Join(a.AddressList, "\n"), formatDeployment(latestDeployment(l))})
+		}
+		apps.Render()
+		fmt.Println()
+	},
+}
+
+// return the most recent deployment of the list
+func latestDeployment(l []*models.Deployment) (latest *models.Deployment) {
+	for _, d := range l {
+		if latest == nil || time.Time(d.When).After(time.Time(latest.When)) {
+			latest = d
+		}
+	}
+	return
+}
+
+func formatDeployment(d *models.Deployment) string {
+	if d == nil {
+		return "-"
+	}
+	s := time.Time(d.When).Format("2006-01-02 15:04")
+	if d.Description != nil && *d.Description != "" {
+		s = fmt.Sprintf("%s %s", s, *d.Description)
+	}
+	if d.Error != "" {
+		s = fmt.Sprintf("%s (failed: %s)", s, d.Error)
+	}
+	return s
+}
+
 var getTeamsCmd = &cobra.Command{
 	Use:   "teams",
 	Short: "Get teams",
@@ -95,20 +309,71 @@ If the user already is member of the team, you will get an error.
 			Fatalf(cmd, "user e-mail is required")
 		}
 		tc := NewTeresa()
-		err := tc.AddUserToTeam(teamNameFlag, userEmailFlag)
-		if err == nil {
-			log.Infof("user [%s] is now member of the team [%s]", userEmailFlag, teamNameFlag)
-			return
+		if err := tc.AddUserToTeam(teamNameFlag, userEmailFlag); err != nil {
+			log.Fatal(teamUserErrorMessage(err))
 		}
-		if err.Code() == 500 {
-			log.Fatalf("Error with the command")
+		log.Infof("user [%s] is now member of the team [%s]", userEmailFlag, teamNameFlag)
+	},
+}
+
+var getTeamUsersCmd = &cobra.Command{
+	Use:   "team-users",
+	Short: "Get the members of a team",
+	Long: `List the members of a team. The ADMIN column is the admin role of the
+user, teams have no member roles.
+
+eg.:
+
+	$ teresa get team-users --team my-team
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if teamNameFlag == "" {
+			Fatalf(cmd, "team name is required")
+		}
+		tc := NewTeresa()
+		team, err := tc.GetTeamDetail(tc.GetTeamID(teamNameFlag))
+		if err != nil {
+			log.Fatalf("Failed to retrieve the team: %s", err)
 		}
-		if err.Code() == 422 {
-			log.Fatal(err.Payload.Message)
+
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{"NAME", "EMAIL", "ADMIN"})
+		table.SetAlignment(tablewriter.ALIGN_LEFT)
+		table.SetAutoWrapText(false)
+		for _, u := range team.Members {
+			table.Append([]string{*u.Name, *u.Email, yesNo(u.IsAdmin != nil && *u.IsAdmin)})
 		}
+		table.Render()
 	},
 }
 
+// describe the errors returned by the server when managing the team members
+func teamUserErrorMessage(err error) string {
+	code, msg := 0, err.Error()
+	switch e := err.(type) {
+	case *teams.AddUserToTeamDefault:
+		code = e.Code()
+		if e.Payload != nil {
+			msg = e.Payload.Message
+		}
+	case *apiError:
+		code, msg = e.Code(), e.payload.Message
+	}
+	switch code {
+	case 401:
+		return "You are not logged in, run: teresa login"
+	case 403:
+		return "You are not allowed to manage the members of this team"
+	case 404:
+		return "Team or user not found"
+	case 422:
+		return msg
+	case 500:
+		return fmt.Sprintf("Error with the command: %s", msg)
+	}
+	return msg
+}
+
 func init() {
 	createCmd.AddCommand(teamCmd)
 	teamCmd.Flags().StringVarP(&teamNameFlag, "name", "n", "", "team name [required]")
@@ -116,12 +381,22 @@ func init() {
 	teamCmd.Flags().StringVarP(&teamURLFlag, "url", "u", "", "team site's URL, if any")
 
 	deleteCmd.AddCommand(deleteTeamCmd)
-	deleteTeamCmd.Flags().Int64Var(&teamIDFlag, "id", 0, "team ID [required]")
+	deleteTeamCmd.Flags().Int64Var(&teamIDFlag, "id", 0, "team ID, instead of the name")
+	deleteTeamCmd.Flags().BoolVarP(&yesFlag, "yes", "y", false, "don't ask for confirmation")
 
 	getCmd.AddCommand(getTeamsCmd)
+	getCmd.AddCommand(getTeamCmd)
+
+	setCmd.AddCommand(setTeamCmd)
+	setTeamCmd.Flags().StringVarP(&teamEmailFlag, "email", "e", "", "team email")
+	setTeamCmd.Flags().StringVarP(&teamURLFlag, "url", "u", "", "team site's URL")
+	setTeamCmd.Flags().StringVar(&teamRenameFlag, "rename", "", "new team name")
 
 	// add user to team
 	addCmd.AddCommand(addUserToTeamCmd)
 	addUserToTeamCmd.Flags().StringVar(&userEmailFlag, "email", "", "user email [required]")
 	addUserToTeamCmd.Flags().StringVar(&teamNameFlag, "team", "", "team name [required]")
+
+	getCmd.AddCommand(getTeamUsersCmd)
+	getTeamUsersCmd.Flags().StringVar(&teamNameFlag, "team", "", "team name [required]")
 }
@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/luizalabs/teresa-api/client/teams"
+	"github.com/luizalabs/teresa-api/models"
+)
+
+func TestTeamUserErrorMessage(t *testing.T) {
+	var tests = []struct {
+		err      error
+		expected string
+	}{
+		{teams.NewAddUserToTeamDefault(401), "not logged in"},
+		{teams.NewAddUserToTeamDefault(403), "not allowed"},
+		{&teams.AddUserToTeamDefault{Payload: &models.Error{Message: "already a member"}}, "already a member"},
+		{&apiError{code: 422, payload: &models.Error{Message: "invalid email"}}, "invalid email"},
+		{&apiError{code: 500, payload: &models.Error{Message: "boom"}}, "Error with the command: boom"},
+		{errors.New("connection refused"), "connection refused"},
+	}
+	for _, tt := range tests {
+		if got := teamUserErrorMessage(tt.err); !strings.Contains(got, tt.expected) {
+			t.Errorf("expected the message for %v to contain %q, got %q", tt.err, tt.expected, got)
+		}
+	}
+}
+
+func TestTeamChanges(t *testing.T) {
+	name, email, url, empty := "site", "site@luizalabs.com", "site.luizalabs.com", ""
+	before := &models.Team{ID: 1, Name: &name, Email: "old@luizalabs.com", URL: "old.luizalabs.com"}
+	newName, shortName, badEmail := "checkout", "ab", "not an email"
+
+	var tests = []struct {
+		changes teamChanges
+		ok      bool
+		name    string
+		email   string
+		url     string
+	}{
+		{teamChanges{email: &email}, true, "site", email, "old.luizalabs.com"},
+		{teamChanges{url: &url, name: &newName}, true, "checkout", "old@luizalabs.com", url},
+		{teamChanges{url: &empty}, true, "site", "old@luizalabs.com", ""},
+		{teamChanges{email: &badEmail}, false, "", "", ""},
+		{teamChanges{name: &shortName}, false, "", "", ""},
+	}
+	for _, tt := range tests {
+		after, err := tt.changes.apply(before)
+		if (err == nil) != tt.ok {
+			t.Errorf("expected ok=%v for %+v, got %v", tt.ok, tt.changes, err)
+			continue
+		}
+		if tt.ok && (*after.Name != tt.name || string(after.Email) != tt.email || after.URL != tt.url) {
+			t.Errorf("expected %s %s %s, got %s %s %s", tt.name, tt.email, tt.url, *after.Name, after.Email, after.URL)
+		}
+	}
+	if *before.Name != "site" || before.URL != "old.luizalabs.com" {
+		t.Errorf("expected the original team unchanged, got %+v", before)
+	}
+	if !(teamChanges{}).empty() || (teamChanges{url: &empty}).empty() {
+		t.Error("expected only the changes without attributes to be empty")
+	}
+}
+
+func TestUpdateTeam(t *testing.T) {
+	api := &fakeAPI{responses: map[string]string{
+		"PUT /v1/teams/1": `{"id": 1, "name": "checkout", "email": "checkout@luizalabs.com"}`,
+	}}
+	tc, ts := newTestClient(t, api)
+	defer ts.Close()
+
+	name := "checkout"
+	team, err := tc.UpdateTeam(&models.Team{ID: 1, Name: &name, Email: "checkout@luizalabs.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *team.Name != "checkout" {
+		t.Errorf("expected the renamed team, got %s", *team.Name)
+	}
+	if len(api.requests) != 1 || !strings.Contains(api.requests[0], `"name":"checkout"`) {
+		t.Errorf("expected the team on the request body, got %v", api.requests)
+	}
+}
+
+func TestLatestDeployment(t *testing.T) {
+	// the oldest deployments first, the latest is on the last page
+	items := make([]string, 2*pageSize+1)
+	start := time.Date(2016, 8, 1, 0, 0, 0, 0, time.UTC)
+	for i := range items {
+		when := start.Add(time.Duration(i) * time.Hour).Format(time.RFC3339)
+		items[i] = fmt.Sprintf(`{"uuid": "%d", "description": "deploy %d", "when": "%s"}`, i, i, when)
+	}
+	api := &fakeAPI{lists: map[string][]string{"/v1/teams/1/apps/2/deployments": items}}
+	tc, ts := newTestClient(t, api)
+	defer ts.Close()
+
+	l, err := tc.GetDeployments(1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(l) != len(items) {
+		t.Fatalf("expected %d deployments, got %d", len(items), len(l))
+	}
+	if d := latestDeployment(l); d == nil || *d.Description != fmt.Sprintf("deploy %d", len(items)-1) {
+		t.Errorf("expected the deployment from the last page, got %+v", d)
+	}
+}
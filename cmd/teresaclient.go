@@ -4,14 +4,18 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net/url"
 	"os"
+	"path"
+	"strings"
 	"time"
 
 	"github.com/go-openapi/runtime"
 	"github.com/go-openapi/runtime/client"
 	httptransport "github.com/go-openapi/runtime/client"
 	"github.com/go-openapi/strfmt"
+	"github.com/go-openapi/swag"
 	apiclient "github.com/luizalabs/teresa-api/client"
 	"github.com/luizalabs/teresa-api/client/apps"
 	"github.com/luizalabs/teresa-api/client/auth"
@@ -20,18 +24,27 @@ import (
 	"github.com/luizalabs/teresa-api/client/users"
 	"github.com/luizalabs/teresa-api/models"
 	_ "github.com/prometheus/common/log" // still needed?
+	"github.com/spf13/viper"
 )
 
 // TeresaClient foo bar
 type TeresaClient struct {
 	teresa         *apiclient.Teresa
 	apiKeyAuthFunc runtime.ClientAuthInfoWriter
+	// name of the cluster, used to cache the resolved IDs
+	cluster string
+	// version of the api in use, like v1
+	apiVersion string
+	// transport of the paths outside the api version, like /version
+	root runtime.ClientTransport
 }
 
-// TeresaServer scheme and host where the api server is running
+// TeresaServer scheme, host and path prefix where the api server is running
 type TeresaServer struct {
 	scheme string
 	host   string
+	// path the api is mounted under, like /teresa behind a gateway
+	basePath string
 }
 
 // AppInfo foo bar
@@ -47,9 +60,9 @@ func ParseServerURL(s string) (TeresaServer, error) {
 		return TeresaServer{}, fmt.Errorf("Failed to parse server: %+v\n", err)
 	}
 	if u.Scheme == "" || u.Scheme != "http" && u.Scheme != "https" {
-		return TeresaServer{}, errors.New("accepted server url format: http(s)://hostname[:port]")
+		return TeresaServer{}, errors.New("accepted server url format: http(s)://hostname[:port][/path]")
 	}
-	ts := TeresaServer{scheme: u.Scheme, host: u.Host}
+	ts := TeresaServer{scheme: u.Scheme, host: u.Host, basePath: strings.TrimRight(u.Path, "/")}
 	return ts, nil
 }
 
@@ -63,26 +76,65 @@ func NewTeresa() TeresaClient {
 	if err != nil {
 		log.Fatalf("Failed to get current cluster name, err: %+v\n", err)
 	}
-	cluster := cfg.Clusters[n]
-	suffix := "/v1"
-
-	tc := TeresaClient{teresa: apiclient.Default}
-	log.Debugf(`Setting new teresa client. server: %s, api suffix: %s`, cluster.Server, suffix)
+	cluster, ok := cfg.Clusters[n]
+	if !ok {
+		log.Fatalf("Cluster \"%s\" not configured yet", n)
+	}
+	tc, err := newTeresaClient(cluster)
+	if err != nil {
+		log.Fatal(err)
+	}
+	tc.cluster = n
+	return tc
+}
 
+// create a client for the cluster
+func newTeresaClient(cluster clusterConfig) (TeresaClient, error) {
 	ts, err := ParseServerURL(cluster.Server)
 	if err != nil {
-		log.Fatal(err)
+		return TeresaClient{}, err
 	}
+	suffix := path.Join("/", ts.basePath, cluster.apiVersion())
+	log.Debugf(`Setting new teresa client. server: %s, api suffix: %s`, cluster.Server, suffix)
 
 	client.DefaultTimeout = 5 * time.Minute // 5 minutes to wait deploy proccess
 	c := client.New(ts.host, suffix, []string{ts.scheme})
+	if c.Transport, err = newHTTPTransport(cluster); err != nil {
+		return TeresaClient{}, err
+	}
+	t := newRetryTransport(c, viper.GetInt("retries"), viper.GetDuration("retry_backoff"))
+	root := client.New(ts.host, path.Join("/", ts.basePath), []string{ts.scheme})
+	root.Transport = c.Transport
+	for _, r := range []*client.Runtime{c, root} {
+		r.Consumers[runtime.TextMime] = errorPageConsumer()
+		r.Consumers[htmlMime] = errorPageConsumer()
+	}
 
-	tc.teresa.SetTransport(c)
-
+	tc := TeresaClient{
+		teresa:     apiclient.New(t, strfmt.Default),
+		apiVersion: cluster.apiVersion(),
+		root:       newRetryTransport(root, viper.GetInt("retries"), viper.GetDuration("retry_backoff")),
+	}
 	if cluster.Token != "" {
 		tc.apiKeyAuthFunc = httptransport.APIKeyAuth("Authorization", "header", cluster.Token)
 	}
-	return tc
+	return tc, nil
+}
+
+const htmlMime = "text/html"
+
+// consumer of the plain text and html error pages of gateways and proxies,
+// like a 502 from the ingress. Without it the runtime fails with a "no
+// consumer" error before the operation reads the status code. The page is
+// only kept when a string is expected, the error payloads stay empty
+func errorPageConsumer() runtime.Consumer {
+	return runtime.ConsumerFunc(func(r io.Reader, data interface{}) error {
+		b, err := ioutil.ReadAll(r)
+		if s, ok := data.(*string); ok {
+			*s = string(b)
+		}
+		return err
+	})
 }
 
 // Login login the user
@@ -109,19 +161,41 @@ func (tc TeresaClient) CreateTeam(name, email, URL string) (*models.Team, error)
 	return r.Payload, nil
 }
 
+// UpdateTeam replaces the team name, email and url
+func (tc TeresaClient) UpdateTeam(team *models.Team) (*models.Team, error) {
+	params := teams.NewUpdateTeamParams()
+	params.TeamID = team.ID
+	params.WithBody(team)
+	r, err := tc.teresa.Teams.UpdateTeam(params, tc.apiKeyAuthFunc)
+	if err != nil {
+		return nil, err
+	}
+	// the team may have been renamed
+	tc.forgetTeam(team.ID)
+	return r.Payload, nil
+}
+
 // DeleteTeam Deletes a team
 func (tc TeresaClient) DeleteTeam(ID int64) error {
 	params := teams.NewDeleteTeamParams()
 	params.TeamID = ID
 	_, err := tc.teresa.Teams.DeleteTeam(params, tc.apiKeyAuthFunc)
+	if err == nil {
+		tc.forgetTeam(ID)
+	}
 	return err
 }
 
 // CreateApp creates an user
 func (tc TeresaClient) CreateApp(name string, scale int64, teamID int64) (app *models.App, err error) {
+	return tc.CreateAppFromModel(teamID, &models.App{Name: &name, Scale: &scale})
+}
+
+// CreateAppFromModel creates an app with all the attributes of the model
+func (tc TeresaClient) CreateAppFromModel(teamID int64, app *models.App) (*models.App, error) {
 	params := apps.NewCreateAppParams()
 	params.TeamID = teamID
-	params.WithBody(&models.App{Name: &name, Scale: &scale})
+	params.WithBody(app)
 	r, err := tc.teresa.Apps.CreateApp(params, tc.apiKeyAuthFunc)
 	if err != nil {
 		return nil, err
@@ -129,14 +203,35 @@ func (tc TeresaClient) CreateApp(name string, scale int64, teamID int64) (app *m
 	return r.Payload, nil
 }
 
-// GetApps return apps for a specific team
-func (tc TeresaClient) GetApps(teamID int64) (app []*models.App, err error) {
-	params := apps.NewGetAppsParams().WithTeamID(teamID)
-	r, err := tc.teresa.Apps.GetApps(params, tc.apiKeyAuthFunc)
-	if err != nil {
-		return nil, err
+// DeleteApp deletes an app and all its deployments
+func (tc TeresaClient) DeleteApp(teamID, appID int64) error {
+	params := func(r runtime.ClientRequest, reg strfmt.Registry) error {
+		if err := r.SetPathParam("team_id", swag.FormatInt64(teamID)); err != nil {
+			return err
+		}
+		return r.SetPathParam("app_id", swag.FormatInt64(appID))
+	}
+	if err := tc.submit("deleteApp", "DELETE", "/teams/{team_id}/apps/{app_id}", params, nil); err != nil {
+		return err
+	}
+	tc.forgetApp(teamID, appID)
+	return nil
+}
+
+// GetApps return apps for a specific team, fetching every page
+func (tc TeresaClient) GetApps(teamID int64) (appsList []*models.App, err error) {
+	limit := int64(pageSize)
+	for since := int64(0); ; since += limit {
+		params := apps.NewGetAppsParams().WithTeamID(teamID).WithLimit(&limit).WithSince(&since)
+		r, err := tc.teresa.Apps.GetApps(params, tc.apiKeyAuthFunc)
+		if err != nil {
+			return nil, err
+		}
+		appsList = append(appsList, r.Payload.Items...)
+		if int64(len(r.Payload.Items)) < limit {
+			return appsList, nil
+		}
 	}
-	return r.Payload.Items, nil
 }
 
 // GetAppDetail Create app attributes
@@ -161,6 +256,84 @@ func (tc TeresaClient) CreateUser(name, email, password string, isAdmin bool) (u
 	return r.Payload, nil
 }
 
+// GetUsers returns all the users, fetching every page
+func (tc TeresaClient) GetUsers() (usersList []*models.User, err error) {
+	limit := int64(pageSize)
+	for since := int64(0); ; since += limit {
+		params := users.NewGetUsersParams().WithLimit(&limit).WithSince(&since)
+		r, err := tc.teresa.Users.GetUsers(params, tc.apiKeyAuthFunc)
+		if err != nil {
+			return nil, err
+		}
+		usersList = append(usersList, r.Payload.Items...)
+		if int64(len(r.Payload.Items)) < limit {
+			return usersList, nil
+		}
+	}
+}
+
+// GetUserByEmail returns the user with the given email
+func (tc TeresaClient) GetUserByEmail(email string) (*models.User, error) {
+	l, err := tc.GetUsers()
+	if err != nil {
+		return nil, err
+	}
+	for _, u := range l {
+		if *u.Email == email {
+			return u, nil
+		}
+	}
+	return nil, newSysError(fmt.Sprintf(`User "%s" not found`, email))
+}
+
+// GetUserDetails returns the user with its teams
+func (tc TeresaClient) GetUserDetails(userID int64) (*models.User, error) {
+	params := users.NewGetUserDetailsParams().WithUserID(userID)
+	r, err := tc.teresa.Users.GetUserDetails(params, tc.apiKeyAuthFunc)
+	if err != nil {
+		return nil, err
+	}
+	return r.Payload, nil
+}
+
+// UpdateUser replaces the user attributes. The generated client can't send
+// the user on the request body, so the operation is submitted by hand
+func (tc TeresaClient) UpdateUser(user *models.User) (*models.User, error) {
+	if err := validateUserUpdate(user); err != nil {
+		return nil, err
+	}
+	params := func(r runtime.ClientRequest, reg strfmt.Registry) error {
+		if err := r.SetPathParam("user_id", swag.FormatInt64(user.ID)); err != nil {
+			return err
+		}
+		return r.SetBodyParam(&userUpdate{Email: user.Email, Name: user.Name, IsAdmin: user.IsAdmin})
+	}
+	updated := new(models.User)
+	if err := tc.submit("updateUser", "PUT", "/users/{user_id}", params, updated); err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// userUpdate is the body of the user update. The updateUser operation of
+// the vendored api spec declares no body, so only the fields changed by set
+// user are sent. The password is left out: the api never returns it and a
+// null one fails the User model validation on the server. passwd changes it
+type userUpdate struct {
+	Email   *string `json:"email"`
+	Name    *string `json:"name"`
+	IsAdmin *bool   `json:"isAdmin"`
+}
+
+// validate the user fields sent on the update, all but the password
+func validateUserUpdate(user *models.User) error {
+	u := *user
+	unchanged := "unchanged"
+	u.Password = &unchanged
+	u.Teams = nil
+	return u.Validate(strfmt.Default)
+}
+
 // DeleteUser Delete an user
 func (tc TeresaClient) DeleteUser(ID int64) error {
 	params := users.NewDeleteUserParams()
@@ -178,60 +351,52 @@ func (tc TeresaClient) Me() (user *models.User, err error) {
 	return r.Payload, nil
 }
 
-// GetAppInfo return teamID and appID
+// GetAppInfo return teamID and appID, exiting if they can't be resolved
 func (tc TeresaClient) GetAppInfo(teamName, appName string) (appInfo AppInfo) {
-	me, err := tc.Me()
+	appInfo, err := tc.ResolveAppInfo(teamName, appName)
 	if err != nil {
-		log.Fatalf("unable to get user information: %s", err)
-	}
-	if len(me.Teams) > 1 && teamName == "" {
-		log.Fatalln("User is in more than one team and provided none")
-	}
-	for _, t := range me.Teams {
-		if teamName == "" || *t.Name == teamName {
-			appInfo.TeamID = t.ID
-			for _, a := range t.Apps {
-				if *a.Name == appName {
-					appInfo.AppID = a.ID
-					break
-				}
-			}
-			break
-		}
-	}
-	if appInfo.TeamID == 0 || appInfo.AppID == 0 {
-		log.Fatalf("Invalid Team [%s] or App [%s]\n", teamName, appName)
+		log.Fatal(err)
 	}
 	return
 }
 
-// GetTeamID returns teamID from team_name
+// GetTeamID returns teamID from team_name, exiting if it can't be resolved
 func (tc TeresaClient) GetTeamID(teamName string) (teamID int64) {
-	me, err := tc.Me()
+	teamID, err := tc.ResolveTeamID(teamName)
 	if err != nil {
-		log.Fatalf("unable to get user information: %s", err)
-	}
-	if len(me.Teams) > 1 && teamName == "" {
-		log.Fatalln("User is in more than one team and provided none")
-	}
-	for _, t := range me.Teams {
-		if teamName == "" || *t.Name == teamName {
-			return t.ID
-		}
+		log.Fatal(err)
 	}
-
-	log.Fatalf("Invalid Team [%s]\n", teamName)
 	return
 }
 
-// GetTeams returns a list with my teams
+// GetTeams returns a list with all the teams, fetching every page
 func (tc TeresaClient) GetTeams() (teamsList []*models.Team, err error) {
-	params := teams.NewGetTeamsParams()
-	r, err := tc.teresa.Teams.GetTeams(params, tc.apiKeyAuthFunc)
+	limit := int64(pageSize)
+	for since := int64(0); ; since += limit {
+		params := teams.NewGetTeamsParams().WithLimit(&limit).WithSince(&since)
+		r, err := tc.teresa.Teams.GetTeams(params, tc.apiKeyAuthFunc)
+		if err != nil {
+			return nil, err
+		}
+		teamsList = append(teamsList, r.Payload.Items...)
+		if int64(len(r.Payload.Items)) < limit {
+			return teamsList, nil
+		}
+	}
+}
+
+// GetTeamByName returns the team with the given name
+func (tc TeresaClient) GetTeamByName(name string) (*models.Team, error) {
+	l, err := tc.GetTeams()
 	if err != nil {
 		return nil, err
 	}
-	return r.Payload.Items, nil
+	for _, t := range l {
+		if *t.Name == name {
+			return t, nil
+		}
+	}
+	return nil, newSysError(fmt.Sprintf(`Team "%s" not found`, name))
 }
 
 // CreateDeploy creates a new deploy
@@ -249,6 +414,35 @@ func (tc TeresaClient) CreateDeploy(teamID, appID int64, description string, tar
 	return r.Payload, err
 }
 
+// GetDeployments returns all the deployments of an app, fetching every page
+func (tc TeresaClient) GetDeployments(teamID, appID int64) (deploys []*models.Deployment, err error) {
+	limit := int64(pageSize)
+	for since := int64(0); ; since += limit {
+		params := deployments.NewGetDeploymentsParams().WithTeamID(teamID).WithAppID(appID).WithLimit(&limit).WithSince(&since)
+		r, err := tc.teresa.Deployments.GetDeployments(params, tc.apiKeyAuthFunc)
+		if err != nil {
+			return nil, err
+		}
+		deploys = append(deploys, r.Payload.Items...)
+		if int64(len(r.Payload.Items)) < limit {
+			return deploys, nil
+		}
+	}
+}
+
+// UpdateApp replaces the app attributes
+func (tc TeresaClient) UpdateApp(teamID, appID int64, app *models.App) (*models.App, error) {
+	params := apps.NewUpdateAppParams()
+	params.TeamID = teamID
+	params.AppID = appID
+	params.WithBody(app)
+	r, err := tc.teresa.Apps.UpdateApp(params, tc.apiKeyAuthFunc)
+	if err != nil {
+		return nil, err
+	}
+	return r.Payload, nil
+}
+
 // PartialUpdateApp partial updates app... for now, updates only envvars
 func (tc TeresaClient) PartialUpdateApp(teamID, appID int64, operations []*models.PatchAppRequest) error {
 	p := apps.NewPartialUpdateAppParams()
@@ -262,14 +456,75 @@ func (tc TeresaClient) PartialUpdateApp(teamID, appID int64, operations []*model
 
 // AddUserToTeam adds a user (by email) to a team.
 // if the user is already part of the team, returns error
-func (tc TeresaClient) AddUserToTeam(team, userEmail string) *teams.AddUserToTeamDefault {
+func (tc TeresaClient) AddUserToTeam(team, userEmail string) error {
 	p := teams.NewAddUserToTeamParams()
 	p.TeamName = team
 	email := strfmt.Email(userEmail)
 	p.User.Email = &email
 	_, err := tc.teresa.Teams.AddUserToTeam(p, tc.apiKeyAuthFunc)
+	return err
+}
+
+// GetTeamDetail returns the team with its members and apps
+func (tc TeresaClient) GetTeamDetail(teamID int64) (*models.Team, error) {
+	params := teams.NewGetTeamDetailParams().WithTeamID(teamID)
+	r, err := tc.teresa.Teams.GetTeamDetail(params, tc.apiKeyAuthFunc)
 	if err != nil {
-		return err.(*teams.AddUserToTeamDefault)
+		return nil, err
 	}
-	return nil
+	return r.Payload, nil
+}
+
+// apiError is returned by the operations not covered by the generated
+// client, when the server answers with an error
+type apiError struct {
+	code    int
+	payload *models.Error
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("[%d] %s", e.code, e.payload.Message)
+}
+
+// Code returns the http status code of the response
+func (e *apiError) Code() int { return e.code }
+
+// submit an operation the generated client doesn't have. When result is
+// not nil, the response body is decoded into it
+func (tc TeresaClient) submit(id, method, pathPattern string, params runtime.ClientRequestWriterFunc, result interface{}) error {
+	return tc.submitTo(tc.teresa.Transport, id, method, pathPattern, params, result)
+}
+
+// submit the operation through the given transport, see submit
+func (tc TeresaClient) submitTo(t runtime.ClientTransport, id, method, pathPattern string, params runtime.ClientRequestWriterFunc, result interface{}) error {
+	reader := func(r runtime.ClientResponse, consumer runtime.Consumer) (interface{}, error) {
+		if r.Code() >= 200 && r.Code() < 300 {
+			if result != nil {
+				if err := consumer.Consume(r.Body(), result); err != nil && err != io.EOF {
+					return nil, err
+				}
+			}
+			return nil, nil
+		}
+		e := &apiError{code: r.Code(), payload: new(models.Error)}
+		// gateways and proxies answer errors with plain text or html pages
+		if !strings.Contains(r.GetHeader("Content-Type"), "json") {
+			e.payload.Message = r.Message()
+		} else if err := consumer.Consume(r.Body(), e.payload); err != nil || e.payload.Message == "" {
+			e.payload.Message = r.Message()
+		}
+		return nil, e
+	}
+	_, err := t.Submit(&runtime.ClientOperation{
+		ID:                 id,
+		Method:             method,
+		PathPattern:        pathPattern,
+		ProducesMediaTypes: []string{"application/json"},
+		ConsumesMediaTypes: []string{"application/json"},
+		Schemes:            []string{"http"},
+		Params:             params,
+		Reader:             runtime.ClientResponseReaderFunc(reader),
+		AuthInfo:           tc.apiKeyAuthFunc,
+	})
+	return err
 }
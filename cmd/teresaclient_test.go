@@ -1,6 +1,86 @@
 package cmd
 
-import "testing"
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	httptransport "github.com/go-openapi/runtime/client"
+)
+
+// fakeAPI is an api server answering with canned json bodies, keyed by
+// "METHOD /path" or by the path alone for any method, and with 404 to the
+// other requests. The requests are recorded as "METHOD /path body"
+type fakeAPI struct {
+	responses map[string]string
+	// json items of the list endpoints, keyed by path, paged by the limit
+	// and since query params like the api does
+	lists    map[string][]string
+	requests []string
+}
+
+func (f *fakeAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	b, _ := ioutil.ReadAll(r.Body)
+	f.requests = append(f.requests, strings.TrimSpace(r.Method+" "+r.URL.Path+" "+string(b)))
+	w.Header().Set("Content-Type", "application/json")
+	body, ok := f.responses[r.Method+" "+r.URL.Path]
+	if !ok {
+		body, ok = f.responses[r.URL.Path]
+	}
+	if items, isList := f.lists[r.URL.Path]; isList && r.Method == "GET" {
+		body, ok = `{"items": [`+strings.Join(page(items, r.URL.Query()), ", ")+`]}`, true
+	}
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		body = `{"code": 404, "message": "not found"}`
+	}
+	w.Write([]byte(body))
+}
+
+// the items of the page asked by the query, 20 by default
+func page(items []string, q url.Values) []string {
+	limit, since := 20, 0
+	if l, err := strconv.Atoi(q.Get("limit")); err == nil {
+		limit = l
+	}
+	if s, err := strconv.Atoi(q.Get("since")); err == nil {
+		since = s
+	}
+	if since > len(items) {
+		return nil
+	}
+	if since+limit > len(items) {
+		return items[since:]
+	}
+	return items[since : since+limit]
+}
+
+// json items named prefix0, prefix1... with IDs from 1 and an email, enough
+// for the users, teams and apps lists
+func newTestItems(prefix string, n int) []string {
+	items := make([]string, n)
+	for i := range items {
+		items[i] = fmt.Sprintf(`{"id": %d, "name": "%s%d", "email": "%s%d@luizalabs.com"}`, i+1, prefix, i, prefix, i)
+	}
+	return items
+}
+
+// start a server with the handler and return a client of it, the server
+// must be closed by the caller
+func newTestClient(t *testing.T, h http.Handler) (TeresaClient, *httptest.Server) {
+	ts := httptest.NewServer(h)
+	tc, err := newTeresaClient(clusterConfig{Server: ts.URL})
+	if err != nil {
+		ts.Close()
+		t.Fatal(err)
+	}
+	return tc, ts
+}
 
 func TestParseURL(t *testing.T) {
 	goodUrls := []string{
@@ -27,3 +107,78 @@ func TestParseURL(t *testing.T) {
 		}
 	}
 }
+
+func TestParseURLPathPrefix(t *testing.T) {
+	if _, err := ParseServerURL("https://gw.myserver.com/teresa/"); err != nil {
+		t.Errorf("Parsing should have passed for an url with a path, error: %+v", err)
+	}
+	if _, err := ParseServerURL("ftp://myserver.com/teresa"); err == nil {
+		t.Error("Parsing should have failed for an url with an unsupported scheme")
+	}
+}
+
+func TestClusterPathPrefix(t *testing.T) {
+	var path string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		if r.URL.Path != "/teresa/v2/users/me" {
+			http.NotFound(w, r)
+			return
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer ts.Close()
+
+	if err := checkCluster(clusterConfig{Server: ts.URL + "/teresa/", APIVersion: "v2"}); err != nil {
+		t.Errorf("expected the api to be found under the prefix, got %v", err)
+	}
+	if err := checkCluster(clusterConfig{Server: ts.URL + "/teresa"}); err == nil {
+		t.Errorf("expected an error for the wrong api version, the request went to %s", path)
+	}
+	if err := checkCluster(clusterConfig{Server: ts.URL}); err == nil {
+		t.Errorf("expected an error without the prefix, the request went to %s", path)
+	}
+}
+
+func TestDeleteApp(t *testing.T) {
+	var method, path string
+	tc, ts := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, path = r.Method, r.URL.Path
+		if r.Header.Get("Authorization") != "token" {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"code": 401, "message": "unauthorized"}`))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	err := tc.DeleteApp(1, 2)
+	if e, ok := err.(*apiError); !ok || e.Code() != 401 || e.payload.Message != "unauthorized" {
+		t.Errorf("expected an unauthorized apiError, got %#v", err)
+	}
+
+	tc.apiKeyAuthFunc = httptransport.APIKeyAuth("Authorization", "header", "token")
+	if err = tc.DeleteApp(1, 2); err != nil {
+		t.Fatal(err)
+	}
+	if method != "DELETE" || path != "/v1/teams/1/apps/2" {
+		t.Errorf("expected DELETE /v1/teams/1/apps/2, got %s %s", method, path)
+	}
+}
+
+func TestClusterGatewayNotFound(t *testing.T) {
+	// gateways answer the unknown paths with html pages
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("<html><body>404 Not Found</body></html>"))
+	}))
+	defer ts.Close()
+
+	err := checkCluster(clusterConfig{Server: ts.URL + "/teresa"})
+	if err == nil || !strings.Contains(err.Error(), "Teresa api not found") {
+		t.Errorf("expected the api not found error, got %v", err)
+	}
+}
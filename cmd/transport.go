@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path/filepath"
+)
+
+// build the http transport for the cluster, trusting its CA bundle and
+// presenting its client certificate, when configured. Requests go through
+// the cluster proxy or, without one, the HTTPS_PROXY/NO_PROXY env vars
+func newHTTPTransport(cluster clusterConfig) (*http.Transport, error) {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	tlsConfig, err := newTLSConfig(cluster)
+	if err != nil {
+		return nil, err
+	}
+	t.TLSClientConfig = tlsConfig
+	if cluster.ProxyURL != "" {
+		u, err := parseProxyURL(cluster.ProxyURL)
+		if err != nil {
+			return nil, err
+		}
+		t.Proxy = http.ProxyURL(u)
+	}
+	return t, nil
+}
+
+// the proxy can be an http(s) proxy or a socks5 one, like an ssh tunnel
+// (ssh -D 1080 bastion)
+func parseProxyURL(s string) (*url.URL, error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse the proxy url: %s", err)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return nil, newSysError("accepted proxy url format: (http|https|socks5)://[user:password@]hostname:port")
+	}
+	if u.Host == "" {
+		return nil, newSysError("Proxy host not provided")
+	}
+	return u, nil
+}
+
+func newTLSConfig(cluster clusterConfig) (*tls.Config, error) {
+	c := &tls.Config{InsecureSkipVerify: cluster.InsecureSkipTLSVerify}
+	if cluster.CAFile != "" {
+		b, err := ioutil.ReadFile(cluster.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read the CA file: %s", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			log.WithError(err).Debug("System cert pool not available, trusting only the CA file")
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(b) {
+			return nil, fmt.Errorf("No PEM certificates found in the CA file %s", cluster.CAFile)
+		}
+		c.RootCAs = pool
+	}
+	if cluster.ClientCert != "" || cluster.ClientKey != "" {
+		if cluster.ClientCert == "" || cluster.ClientKey == "" {
+			return nil, newSysError("Client certificate and key must be provided together")
+		}
+		cert, err := tls.LoadX509KeyPair(cluster.ClientCert, cluster.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to load the client certificate: %s", err)
+		}
+		c.Certificates = []tls.Certificate{cert}
+	}
+	return c, nil
+}
+
+// make the cert paths absolute, so the cluster works from any directory
+func absCertPaths(cluster *clusterConfig) error {
+	for _, p := range []*string{&cluster.CAFile, &cluster.ClientCert, &cluster.ClientKey} {
+		if *p == "" {
+			continue
+		}
+		a, err := filepath.Abs(*p)
+		if err != nil {
+			return err
+		}
+		*p = a
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"encoding/pem"
+	"io"
+	"io/ioutil"
+	stdlog "log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+// api answering the current user, enough to check the client connects
+func newMeTestAPI() *fakeAPI {
+	return &fakeAPI{responses: map[string]string{"/v1/users/me": `{"email": "gopher@luizalabs.com"}`}}
+}
+
+// tls test server not logging the handshake failures, expected when the
+// client doesn't trust its certificate
+func newTLSTestServer(h http.Handler) *httptest.Server {
+	ts := httptest.NewUnstartedServer(h)
+	ts.Config.ErrorLog = stdlog.New(ioutil.Discard, "", 0)
+	ts.StartTLS()
+	return ts
+}
+
+func TestClusterTLS(t *testing.T) {
+	ts := newTLSTestServer(newMeTestAPI())
+	defer ts.Close()
+
+	dir, err := ioutil.TempDir("", "teresa")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	caFile := filepath.Join(dir, "ca.pem")
+	b := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ts.Certificate().Raw})
+	if err = ioutil.WriteFile(caFile, b, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var tests = []struct {
+		cluster clusterConfig
+		ok      bool
+	}{
+		{clusterConfig{Server: ts.URL}, false},
+		{clusterConfig{Server: ts.URL, CAFile: caFile}, true},
+		{clusterConfig{Server: ts.URL, InsecureSkipTLSVerify: true}, true},
+	}
+	for _, tt := range tests {
+		tc, err := newTeresaClient(tt.cluster)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = tc.Me(); (err == nil) != tt.ok {
+			t.Errorf("expected ok=%v with %+v, got %v", tt.ok, tt.cluster, err)
+		}
+	}
+
+	if _, err = newTeresaClient(clusterConfig{Server: ts.URL, CAFile: filepath.Join(dir, "missing.pem")}); err == nil {
+		t.Error("expected an error for a missing CA file")
+	}
+	if _, err = newTeresaClient(clusterConfig{Server: ts.URL, ClientCert: caFile}); err == nil {
+		t.Error("expected an error for a client certificate without key")
+	}
+}
+
+func TestClusterProxy(t *testing.T) {
+	api := newMeTestAPI()
+	proxy := httptest.NewServer(api)
+	defer proxy.Close()
+
+	tc, err := newTeresaClient(clusterConfig{Server: "http://teresa.invalid", ProxyURL: proxy.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = tc.Me(); err != nil {
+		t.Fatal(err)
+	}
+	if len(api.requests) != 1 || api.requests[0] != "GET /v1/users/me" {
+		t.Errorf("expected the request to go through the proxy, got %v", api.requests)
+	}
+
+	for _, u := range []string{"ftp://proxy:21", "socks5://", "::"} {
+		if _, err = newTeresaClient(clusterConfig{Server: "http://teresa.invalid", ProxyURL: u}); err == nil {
+			t.Errorf("expected an error for the proxy url %q", u)
+		}
+	}
+}
+
+func TestClusterSocksProxy(t *testing.T) {
+	ts := httptest.NewServer(newMeTestAPI())
+	defer ts.Close()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	dialed := make(chan string, 1)
+	go serveSocks5(l, dialed)
+
+	tc, err := newTeresaClient(clusterConfig{Server: ts.URL, ProxyURL: "socks5://" + l.Addr().String()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = tc.Me(); err != nil {
+		t.Fatal(err)
+	}
+	if addr := <-dialed; addr != ts.Listener.Addr().String() {
+		t.Errorf("expected the proxy to dial %s, got %s", ts.Listener.Addr(), addr)
+	}
+}
+
+// minimal socks5 server (no auth, CONNECT to ipv4 addresses only)
+func serveSocks5(l net.Listener, dialed chan<- string) {
+	for {
+		c, err := l.Accept()
+		if err != nil {
+			return
+		}
+		go func(c net.Conn) {
+			defer c.Close()
+			// greeting: version, number of methods and the methods
+			b := make([]byte, 262)
+			if _, err := io.ReadFull(c, b[:2]); err != nil {
+				return
+			}
+			if _, err := io.ReadFull(c, b[:b[1]]); err != nil {
+				return
+			}
+			c.Write([]byte{5, 0})
+			// request: version, CONNECT, reserved, ipv4, address and port
+			if _, err := io.ReadFull(c, b[:10]); err != nil || b[3] != 1 {
+				return
+			}
+			addr := net.JoinHostPort(net.IP(b[4:8]).String(), strconv.Itoa(int(b[8])<<8|int(b[9])))
+			dialed <- addr
+			target, err := net.Dial("tcp", addr)
+			if err != nil {
+				return
+			}
+			defer target.Close()
+			c.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+			go io.Copy(target, c)
+			io.Copy(c, target)
+		}(c)
+	}
+}
@@ -0,0 +1,368 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/luizalabs/teresa-api/models"
+	"github.com/mattn/go-isatty"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+var uiCmd = &cobra.Command{
+	Use:   "ui",
+	Short: "Interactive dashboard of your teams and apps",
+	Long: `Browse your teams and apps, see the app details and scale, edit the
+env vars or redeploy them, picking the options from menus.
+
+When the output isn't a terminal, like in a pipe, the teams and apps are
+listed instead.
+
+eg.:
+
+	$ teresa ui
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		tc := NewTeresa()
+		me, err := tc.Me()
+		if err != nil {
+			log.Fatalf("Failed to get your teams: %s", err)
+		}
+		if !isatty.IsTerminal(os.Stdout.Fd()) || !isatty.IsTerminal(os.Stdin.Fd()) {
+			printTeamsAndApps(os.Stdout, me.Teams)
+			return
+		}
+		d := &dashboard{tc: tc, in: bufio.NewReader(os.Stdin), out: os.Stdout, teams: me.Teams}
+		d.run()
+	},
+}
+
+// list the apps of the teams, the non interactive version of the dashboard
+func printTeamsAndApps(w io.Writer, teams []*models.Team) {
+	table := tablewriter.NewWriter(w)
+	table.SetHeader([]string{"TEAM", "APP", "SCALE", "ADDRESS"})
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	table.SetAutoWrapText(false)
+	for _, t := range sortedTeams(teams) {
+		if len(t.Apps) == 0 {
+			table.Append([]string{*t.Name, "-", "", ""})
+		}
+		for _, a := range sortedApps(t.Apps) {
+			table.Append([]string{*t.Name, *a.Name, formatScale(a.Scale), strings.Join(a.AddressList, ", ")})
+		}
+	}
+	table.Render()
+}
+
+// dashboard is the interactive menu of the ui command. Every screen shows
+// the options and reads the choice, until the user quits
+type dashboard struct {
+	tc    TeresaClient
+	in    *bufio.Reader
+	out   io.Writer
+	teams []*models.Team
+}
+
+// actions returned by the screens to the previous one
+const (
+	uiBack = iota
+	uiQuit
+)
+
+func (d *dashboard) run() {
+	for {
+		fmt.Fprintln(d.out, "\nTeams")
+		teams := sortedTeams(d.teams)
+		for i, t := range teams {
+			fmt.Fprintf(d.out, "  %d) %s (%d apps)\n", i+1, *t.Name, len(t.Apps))
+		}
+		c, ok := d.choose("[number] open  r) refresh  q) quit")
+		switch {
+		case !ok || c == "q":
+			return
+		case c == "r":
+			d.refresh()
+		default:
+			if i, ok := d.index(c, len(teams)); ok && d.team(teams[i]) == uiQuit {
+				return
+			}
+		}
+	}
+}
+
+func (d *dashboard) team(t *models.Team) int {
+	for {
+		fmt.Fprintf(d.out, "\nTeam %s\n", *t.Name)
+		apps := sortedApps(t.Apps)
+		if len(apps) == 0 {
+			fmt.Fprintln(d.out, "  no apps yet")
+		}
+		for i, a := range apps {
+			fmt.Fprintf(d.out, "  %d) %s (scale %s)\n", i+1, *a.Name, formatScale(a.Scale))
+		}
+		c, ok := d.choose("[number] open  b) back  q) quit")
+		switch {
+		case !ok || c == "q":
+			return uiQuit
+		case c == "b":
+			return uiBack
+		default:
+			if i, ok := d.index(c, len(apps)); ok && d.app(t, apps[i]) == uiQuit {
+				return uiQuit
+			}
+		}
+	}
+}
+
+func (d *dashboard) app(t *models.Team, a *models.App) int {
+	for {
+		app, err := d.tc.GetAppDetail(t.ID, a.ID)
+		if err != nil {
+			fmt.Fprintf(d.out, "Failed to get the app: %s\n", err)
+			return uiBack
+		}
+		app.ID = a.ID
+		d.printApp(t, app)
+		c, ok := d.choose("s) scale  e) edit env  d) redeploy  r) refresh  b) back  q) quit")
+		switch c {
+		case "s":
+			d.scale(t, app)
+		case "e":
+			d.editEnv(t, app)
+		case "d":
+			d.redeploy(t, app)
+		case "r":
+		case "b":
+			return uiBack
+		default:
+			if !ok || c == "q" {
+				return uiQuit
+			}
+			fmt.Fprintf(d.out, "Invalid option %q\n", c)
+		}
+	}
+}
+
+func (d *dashboard) printApp(t *models.Team, app *models.App) {
+	fmt.Fprintf(d.out, "\nApp %s/%s\n", *t.Name, *app.Name)
+	fmt.Fprintf(d.out, "Scale: %s\n", formatScale(app.Scale))
+	if len(app.AddressList) > 0 {
+		fmt.Fprintln(d.out, "\nAddress:")
+		for _, x := range app.AddressList {
+			fmt.Fprintf(d.out, "  %s\n", x)
+		}
+	}
+	// the values may be secrets and the dashboard stays on the screen, so
+	// only the keys are shown
+	if len(app.EnvVars) > 0 {
+		fmt.Fprintln(d.out, "\nEnv Vars (values hidden):")
+		for _, e := range sortedEnvVars(app.EnvVars) {
+			fmt.Fprintf(d.out, "  %s\n", *e.Key)
+		}
+	}
+	deploys := sortedDeployments(app.DeploymentList)
+	if len(deploys) > 0 {
+		fmt.Fprintln(d.out, "\nDeployments:")
+		for i, x := range deploys {
+			if i == uiMaxDeployments {
+				fmt.Fprintf(d.out, "  ... %d older\n", len(deploys)-i)
+				break
+			}
+			fmt.Fprintf(d.out, "  %s\n", formatDeployment(x))
+		}
+	}
+}
+
+// most recent deployments shown on the app screen
+const uiMaxDeployments = 5
+
+func (d *dashboard) scale(t *models.Team, app *models.App) {
+	s, ok := d.ask(fmt.Sprintf("New scale [%s]: ", formatScale(app.Scale)))
+	if !ok || s == "" {
+		return
+	}
+	scale, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || scale < 0 {
+		fmt.Fprintf(d.out, "Invalid scale %q\n", s)
+		return
+	}
+	update := &models.App{Name: app.Name, Scale: &scale, EnvVars: app.EnvVars}
+	if _, err = d.tc.UpdateApp(t.ID, app.ID, update); err != nil {
+		fmt.Fprintf(d.out, "Failed to scale the app: %s\n", err)
+		return
+	}
+	fmt.Fprintf(d.out, "App scaled to %d\n", scale)
+}
+
+// read the env var changes, one per line, and apply them at once
+func (d *dashboard) editEnv(t *models.Team, app *models.App) {
+	fmt.Fprintln(d.out, "Type KEY=VALUE to set a var and -KEY to unset it, an empty line to finish")
+	desired := make(map[string]string, len(app.EnvVars))
+	for _, e := range app.EnvVars {
+		desired[*e.Key] = *e.Value
+	}
+	for {
+		l, ok := d.ask("env> ")
+		if !ok || l == "" {
+			break
+		}
+		if strings.HasPrefix(l, "-") {
+			delete(desired, strings.TrimPrefix(l, "-"))
+			continue
+		}
+		kv := strings.SplitN(l, "=", 2)
+		if len(kv) != 2 || !envVarKeyRegexp.MatchString(kv[0]) {
+			fmt.Fprintf(d.out, "Invalid env var %q\n", l)
+			continue
+		}
+		desired[kv[0]] = kv[1]
+	}
+	set, unset := diffEnvVars(app.EnvVars, desired)
+	if len(set) == 0 && len(unset) == 0 {
+		fmt.Fprintln(d.out, "No changes")
+		return
+	}
+	printEnvVarsDiff(d.out, set, unset)
+	if !d.confirm("Apply the changes?") {
+		return
+	}
+	if err := d.tc.PartialUpdateApp(t.ID, app.ID, envVarsPatch(set, unset)); err != nil {
+		fmt.Fprintf(d.out, "Failed to update the env vars: %s\n", err)
+		return
+	}
+	fmt.Fprintln(d.out, "Env vars updated")
+}
+
+func (d *dashboard) redeploy(t *models.Team, app *models.App) {
+	folder := "."
+	if project != nil && project.App == *app.Name {
+		folder = project.deployPath()
+	}
+	if f, ok := d.ask(fmt.Sprintf("Folder to deploy [%s]: ", folder)); !ok {
+		return
+	} else if f != "" {
+		folder = f
+	}
+	description, ok := d.ask("Description: ")
+	if !ok || !d.confirm(fmt.Sprintf("Deploy %s to %s/%s?", folder, *t.Name, *app.Name)) {
+		return
+	}
+	a := AppInfo{TeamID: t.ID, AppID: app.ID}
+	if err := deployFolder(d.tc, a, *app.Name, *t.Name, description, folder); err != nil {
+		fmt.Fprintf(d.out, "Failed to deploy: %s\n", err)
+	}
+}
+
+func (d *dashboard) refresh() {
+	me, err := d.tc.Me()
+	if err != nil {
+		fmt.Fprintf(d.out, "Failed to get your teams: %s\n", err)
+		return
+	}
+	d.teams = me.Teams
+}
+
+// show the options and read the choice, false when the input ended
+func (d *dashboard) choose(options string) (string, bool) {
+	fmt.Fprintln(d.out, options)
+	c, ok := d.ask("> ")
+	return strings.ToLower(c), ok
+}
+
+func (d *dashboard) ask(prompt string) (string, bool) {
+	fmt.Fprint(d.out, prompt)
+	s, err := d.in.ReadString('\n')
+	if err != nil && s == "" {
+		return "", false
+	}
+	return strings.TrimSpace(s), true
+}
+
+func (d *dashboard) confirm(question string) bool {
+	a, _ := d.ask(fmt.Sprintf("%s [y/N]: ", question))
+	a = strings.ToLower(a)
+	return a == "y" || a == "yes"
+}
+
+// the 0 based index of a 1 based menu choice
+func (d *dashboard) index(c string, n int) (int, bool) {
+	i, err := strconv.Atoi(c)
+	if err != nil || i < 1 || i > n {
+		fmt.Fprintf(d.out, "Invalid option %q\n", c)
+		return 0, false
+	}
+	return i - 1, true
+}
+
+func printEnvVarsDiff(w io.Writer, set []*models.EnvVar, unset []string) {
+	for _, e := range set {
+		fmt.Fprintf(w, "  + %s=%s\n", *e.Key, *e.Value)
+	}
+	for _, k := range unset {
+		fmt.Fprintf(w, "  - %s\n", k)
+	}
+}
+
+func formatScale(s *int64) string {
+	if s == nil {
+		return "-"
+	}
+	return strconv.FormatInt(*s, 10)
+}
+
+type teamsByName []*models.Team
+
+func (l teamsByName) Len() int           { return len(l) }
+func (l teamsByName) Swap(i, j int)      { l[i], l[j] = l[j], l[i] }
+func (l teamsByName) Less(i, j int) bool { return *l[i].Name < *l[j].Name }
+
+type appsByName []*models.App
+
+func (l appsByName) Len() int           { return len(l) }
+func (l appsByName) Swap(i, j int)      { l[i], l[j] = l[j], l[i] }
+func (l appsByName) Less(i, j int) bool { return *l[i].Name < *l[j].Name }
+
+// most recent first
+type deploymentsByDate []*models.Deployment
+
+func (l deploymentsByDate) Len() int      { return len(l) }
+func (l deploymentsByDate) Swap(i, j int) { l[i], l[j] = l[j], l[i] }
+func (l deploymentsByDate) Less(i, j int) bool {
+	return time.Time(l[i].When).After(time.Time(l[j].When))
+}
+
+func sortedTeams(l []*models.Team) []*models.Team {
+	s := append([]*models.Team(nil), l...)
+	sort.Sort(teamsByName(s))
+	return s
+}
+
+func sortedApps(l []*models.App) []*models.App {
+	s := append([]*models.App(nil), l...)
+	sort.Sort(appsByName(s))
+	return s
+}
+
+func sortedEnvVars(l []*models.EnvVar) []*models.EnvVar {
+	s := append([]*models.EnvVar(nil), l...)
+	sort.Sort(envVarsByKey(s))
+	return s
+}
+
+// the deployments, most recent first
+func sortedDeployments(l []*models.Deployment) []*models.Deployment {
+	s := append([]*models.Deployment(nil), l...)
+	sort.Sort(deploymentsByDate(s))
+	return s
+}
+
+func init() {
+	RootCmd.AddCommand(uiCmd)
+}
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/luizalabs/teresa-api/models"
+)
+
+func newTestTeams() []*models.Team {
+	site, webapi, worker := "site", "webapi", "worker"
+	scale := int64(2)
+	return []*models.Team{{
+		ID:   1,
+		Name: &site,
+		Apps: []*models.App{{ID: 11, Name: &worker}, {ID: 10, Name: &webapi, Scale: &scale}},
+	}}
+}
+
+func TestDashboard(t *testing.T) {
+	api := &fakeAPI{responses: map[string]string{
+		"/v1/teams/1/apps/10": `{"id": 10, "name": "webapi", "scale": 2, "envVars": [{"key": "OLD", "value": "1"}], "addressList": ["webapi.site.com"]}`,
+	}}
+	tc, ts := newTestClient(t, api)
+	defer ts.Close()
+
+	// open site, then webapi, scale it to 3, set FOO and unset OLD, then quit
+	in := "1\n1\ns\n3\ne\nFOO=bar\n-OLD\n\ny\nq\n"
+	var out bytes.Buffer
+	d := &dashboard{tc: tc, in: bufio.NewReader(strings.NewReader(in)), out: &out, teams: newTestTeams()}
+	d.run()
+
+	for _, e := range []string{"1) site (2 apps)", "1) webapi (scale 2)", "2) worker (scale -)", "App site/webapi", "webapi.site.com", "Env Vars (values hidden):\n  OLD\n", "+ FOO=bar", "- OLD", "Env vars updated"} {
+		if !strings.Contains(out.String(), e) {
+			t.Errorf("expected the output to contain %q, got:\n%s", e, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "OLD: 1") {
+		t.Errorf("expected the env var values to be hidden, got:\n%s", out.String())
+	}
+	var updates []string
+	for _, r := range api.requests {
+		if !strings.HasPrefix(r, "GET") {
+			updates = append(updates, r)
+		}
+	}
+	if len(updates) != 2 || !strings.HasPrefix(updates[0], "PUT /v1/teams/1/apps/10") || !strings.Contains(updates[0], `"scale":3`) {
+		t.Fatalf("expected the app to be scaled and the env vars updated, got %v", updates)
+	}
+	if !strings.HasPrefix(updates[1], "PATCH /v1/teams/1/apps/10") || !strings.Contains(updates[1], `"op":"add"`) || !strings.Contains(updates[1], `"op":"remove"`) {
+		t.Errorf("expected a single patch adding and removing env vars, got %s", updates[1])
+	}
+}
+
+func TestPrintTeamsAndApps(t *testing.T) {
+	var out bytes.Buffer
+	printTeamsAndApps(&out, newTestTeams())
+	lines := strings.Split(out.String(), "\n")
+	if len(lines) < 5 || !strings.Contains(lines[3], "webapi") || !strings.Contains(lines[4], "worker") {
+		t.Errorf("expected the apps sorted by name, got:\n%s", out.String())
+	}
+}
@@ -0,0 +1,288 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// default of the update index url and the key the binaries are signed
+// with, set at build time. The key can't be changed after the build, so a
+// tampered config or environment can't make the cli trust other binaries:
+//
+//	go build -ldflags "-X github.com/luizalabs/teresa-cli/cmd.updateIndexURL=https://... -X github.com/luizalabs/teresa-cli/cmd.updatePublicKey=..."
+var (
+	updateIndexURL  string
+	updatePublicKey string
+)
+
+// largest binary downloaded by the update
+const maxUpdateSize = 200 << 20
+
+// updateManifest is the release index: the latest version and its binaries,
+// keyed by "GOOS/GOARCH"
+type updateManifest struct {
+	Version  string                  `json:"version"`
+	Binaries map[string]updateBinary `json:"binaries"`
+}
+
+type updateBinary struct {
+	// absolute or relative to the index url
+	URL string `json:"url"`
+	// hex encoded sha256 of the binary
+	SHA256 string `json:"sha256"`
+	// base64 encoded ed25519 signature of the release, see updateSignedMessage
+	Signature string `json:"signature"`
+}
+
+// the message signed for each binary: the version, the platform and the
+// sha256 of the binary. Signing the version keeps an old binary from being
+// served as a newer one
+func updateSignedMessage(version, platform string, bin []byte) []byte {
+	sum := sha256.Sum256(bin)
+	return []byte(fmt.Sprintf("teresa %s %s %s", version, platform, hex.EncodeToString(sum[:])))
+}
+
+var updateCmd = &cobra.Command{
+	Use:   "update",
+	Short: "Updates the cli to the latest version",
+	Long: `Download the latest version of the cli and replace the running one.
+
+The binary is checked against the sha256 and the signature in the release
+index before replacing the current one, which is restored if the new one
+doesn't run. The signature covers the version, so an older release can't
+be installed as a newer one.
+
+The release index url can be set with the update_url key of the config
+file or the TERESA_UPDATE_URL env var. The key of the signatures is built
+in the cli.
+
+eg.:
+
+	$ teresa update --check
+	$ teresa update
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		m, err := getUpdateManifest(viper.GetString("update_url"))
+		if err != nil {
+			log.Fatal(err)
+		}
+		if compareVersions(m.Version, version) <= 0 {
+			fmt.Printf("teresa %s is the latest version\n", version)
+			return
+		}
+		fmt.Printf("New version available: %s (current %s)\n", m.Version, version)
+		if checkFlag {
+			return
+		}
+		platform := runtime.GOOS + "/" + runtime.GOARCH
+		b, ok := m.Binaries[platform]
+		if !ok {
+			log.Fatalf("No binary of teresa %s for %s", m.Version, platform)
+		}
+		bin, err := downloadUpdate(m.Version, platform, b, updatePublicKey)
+		if err != nil {
+			log.Fatal(err)
+		}
+		exe, err := os.Executable()
+		if err == nil {
+			exe, err = filepath.EvalSymlinks(exe)
+		}
+		if err != nil {
+			log.Fatalf("Failed to find the running executable: %s", err)
+		}
+		if err = replaceExecutable(exe, bin, checkExecutable); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("teresa updated to %s\n", m.Version)
+	},
+}
+
+// fetch and decode the release index
+func getUpdateManifest(u string) (*updateManifest, error) {
+	if u == "" {
+		return nil, newSysError("Release index url not set, configure update_url or TERESA_UPDATE_URL")
+	}
+	r, err := updateHTTPGet(u)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get the release index: %s", err)
+	}
+	m := &updateManifest{}
+	if err = json.Unmarshal(r, m); err != nil {
+		return nil, fmt.Errorf("Invalid release index: %s", err)
+	}
+	if m.Version == "" {
+		return nil, newSysError("Invalid release index: version not found")
+	}
+	// the binary urls may be relative to the index
+	base, err := url.Parse(u)
+	if err != nil {
+		return nil, err
+	}
+	for k, b := range m.Binaries {
+		ref, err := url.Parse(b.URL)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid release index: %s", err)
+		}
+		b.URL = base.ResolveReference(ref).String()
+		m.Binaries[k] = b
+	}
+	return m, nil
+}
+
+// download the binary of the version, checking its sha256 and signature
+func downloadUpdate(version, platform string, b updateBinary, publicKey string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(publicKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, newSysError("This build of the cli has no key to verify the updates, download the new version by hand")
+	}
+	sig, err := base64.StdEncoding.DecodeString(b.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid signature in the release index: %s", err)
+	}
+	bin, err := updateHTTPGet(b.URL)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to download the update: %s", err)
+	}
+	sum := sha256.Sum256(bin)
+	if hex.EncodeToString(sum[:]) != b.SHA256 {
+		return nil, newSysError("Checksum mismatch, the downloaded binary is corrupted")
+	}
+	if !ed25519.Verify(ed25519.PublicKey(key), updateSignedMessage(version, platform, bin), sig) {
+		return nil, newSysError(fmt.Sprintf("Invalid signature, the downloaded binary wasn't signed by the teresa release key as %s for %s", version, platform))
+	}
+	return bin, nil
+}
+
+func updateHTTPGet(u string) ([]byte, error) {
+	c := &http.Client{Timeout: 5 * time.Minute}
+	r, err := c.Get(u)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Body.Close()
+	if r.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", u, r.Status)
+	}
+	b, err := ioutil.ReadAll(io.LimitReader(r.Body, maxUpdateSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(b) > maxUpdateSize {
+		return nil, fmt.Errorf("%s: larger than %d bytes", u, maxUpdateSize)
+	}
+	return b, nil
+}
+
+// replace the executable with the new binary, restoring the old one if the
+// replacement or the check of the new binary fail
+func replaceExecutable(exe string, bin []byte, check func(string) error) error {
+	dir := filepath.Dir(exe)
+	tmp, err := ioutil.TempFile(dir, filepath.Base(exe)+".new")
+	if err != nil {
+		return fmt.Errorf("Failed to write the new binary: %s", err)
+	}
+	defer os.Remove(tmp.Name())
+	_, err = io.Copy(tmp, bytes.NewReader(bin))
+	if cErr := tmp.Close(); err == nil {
+		err = cErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0755)
+	}
+	if err != nil {
+		return fmt.Errorf("Failed to write the new binary: %s", err)
+	}
+
+	old := exe + ".old"
+	if err = backupExecutable(exe, old); err != nil {
+		return fmt.Errorf("Failed to replace the binary: %s", err)
+	}
+	// on unix the new binary is renamed over the running one at once, so
+	// there is always a binary in place. Windows can't replace a running
+	// binary, which was moved to the backup instead
+	if err = os.Rename(tmp.Name(), exe); err == nil {
+		err = check(exe)
+	}
+	if err != nil {
+		if rErr := os.Rename(old, exe); rErr != nil {
+			return fmt.Errorf("Failed to replace the binary (%s) and to restore the old one (%s), it was kept at %s", err, rErr, old)
+		}
+		return fmt.Errorf("Failed to replace the binary, the old one was restored: %s", err)
+	}
+	if err = os.Remove(old); err != nil {
+		log.WithError(err).WithField("file", old).Debug("Failed to remove the old binary")
+	}
+	return nil
+}
+
+// keep the running binary at old, to restore it if the new one fails. It
+// is moved on windows and linked, or copied, elsewhere
+func backupExecutable(exe, old string) error {
+	os.Remove(old)
+	if runtime.GOOS == "windows" {
+		return os.Rename(exe, old)
+	}
+	if err := os.Link(exe, old); err == nil {
+		return nil
+	}
+	b, err := ioutil.ReadFile(exe)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(old, b, 0755)
+}
+
+// make sure the new binary runs. It runs with an empty home and config, so
+// it neither reads nor migrates the config of the user
+func checkExecutable(exe string) error {
+	home, err := ioutil.TempDir("", "teresa-update")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(home)
+	c := exec.Command(exe, "version", "--only-client")
+	c.Env = isolatedEnv(os.Environ(), home)
+	out, err := c.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("the new binary doesn't run: %s: %s", err, out)
+	}
+	return nil
+}
+
+// the environment without the teresa settings and with the home dir, where
+// the config file is looked for, pointing to the given dir
+func isolatedEnv(env []string, home string) []string {
+	var e []string
+	for _, v := range env {
+		k := strings.ToUpper(strings.SplitN(v, "=", 2)[0])
+		switch {
+		case strings.HasPrefix(k, "TERESA_"), k == "HOME", k == "USERPROFILE", k == "HOMEDRIVE", k == "HOMEPATH":
+			continue
+		}
+		e = append(e, v)
+	}
+	return append(e, "HOME="+home, "USERPROFILE="+home)
+}
+
+func init() {
+	RootCmd.AddCommand(updateCmd)
+	updateCmd.Flags().BoolVar(&checkFlag, "check", false, "Only check if there is a new version")
+}
@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestUpdateDownload(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bin := []byte("new teresa binary")
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/index.json":
+			w.Write([]byte(`{"version": "9.0.0", "binaries": {"linux/amd64": {"url": "/teresa"}}}`))
+		case "/teresa":
+			w.Write(bin)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	m, err := getUpdateManifest(ts.URL + "/index.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Version != "9.0.0" || m.Binaries["linux/amd64"].URL != ts.URL+"/teresa" {
+		t.Errorf("unexpected manifest %+v", m)
+	}
+	if _, err = getUpdateManifest(ts.URL + "/missing.json"); err == nil {
+		t.Error("expected an error for a missing release index")
+	}
+
+	sum := sha256.Sum256(bin)
+	key := base64.StdEncoding.EncodeToString(pub)
+	sign := func(version, platform string, b []byte) string {
+		return base64.StdEncoding.EncodeToString(ed25519.Sign(priv, updateSignedMessage(version, platform, b)))
+	}
+	good := updateBinary{
+		URL:       ts.URL + "/teresa",
+		SHA256:    hex.EncodeToString(sum[:]),
+		Signature: sign("9.0.0", "linux/amd64", bin),
+	}
+	b, err := downloadUpdate("9.0.0", "linux/amd64", good, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != string(bin) {
+		t.Errorf("expected the binary, got %q", b)
+	}
+
+	badSum := good
+	badSum.SHA256 = hex.EncodeToString(make([]byte, sha256.Size))
+	badSig := good
+	badSig.Signature = sign("9.0.0", "linux/amd64", []byte("other binary"))
+	for _, u := range []updateBinary{badSum, badSig} {
+		if _, err = downloadUpdate("9.0.0", "linux/amd64", u, key); err == nil {
+			t.Errorf("expected the verification of %+v to fail", u)
+		}
+	}
+	// a signed older release can't be served as a newer one
+	oldSig := good
+	oldSig.Signature = sign("1.0.0", "linux/amd64", bin)
+	if _, err = downloadUpdate("9.0.0", "linux/amd64", oldSig, key); err == nil {
+		t.Error("expected the signature of another version to be refused")
+	}
+	if _, err = downloadUpdate("9.0.0", "darwin/amd64", good, key); err == nil {
+		t.Error("expected the signature of another platform to be refused")
+	}
+	if _, err = downloadUpdate("9.0.0", "linux/amd64", good, ""); err == nil {
+		t.Error("expected an error without the public key")
+	}
+}
+
+func TestReplaceExecutable(t *testing.T) {
+	dir, err := ioutil.TempDir("", "teresa")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	exe := filepath.Join(dir, "teresa")
+	if err = ioutil.WriteFile(exe, []byte("old"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// the executable is always in place, even while being replaced
+	broken := func(f string) error {
+		if _, err := os.Stat(exe); err != nil {
+			t.Errorf("expected the binary in place during the check: %s", err)
+		}
+		return errors.New("exec format error")
+	}
+	if err = replaceExecutable(exe, []byte("broken"), broken); err == nil {
+		t.Error("expected the replacement to fail")
+	}
+	if b, _ := ioutil.ReadFile(exe); string(b) != "old" {
+		t.Errorf("expected the old binary to be restored, got %q", b)
+	}
+
+	ok := func(string) error { return nil }
+	if err = replaceExecutable(exe, []byte("new"), ok); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := ioutil.ReadFile(exe); string(b) != "new" {
+		t.Errorf("expected the new binary, got %q", b)
+	}
+	if files, _ := ioutil.ReadDir(dir); len(files) != 1 {
+		t.Errorf("expected only the binary to be left, got %d files", len(files))
+	}
+}
+
+func TestIsolatedEnv(t *testing.T) {
+	env := isolatedEnv([]string{"PATH=/bin", "HOME=/home/gopher", "TERESA_CLUSTER=prod", "teresa_debug=true"}, "/tmp/x")
+	expected := []string{"PATH=/bin", "HOME=/tmp/x", "USERPROFILE=/tmp/x"}
+	if strings.Join(env, " ") != strings.Join(expected, " ") {
+		t.Errorf("expected %v, got %v", expected, env)
+	}
+}
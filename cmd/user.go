@@ -1,6 +1,12 @@
 package cmd
 
 import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/luizalabs/teresa-api/models"
+	"github.com/olekukonko/tablewriter"
 	_ "github.com/prometheus/common/log"
 	"github.com/spf13/cobra"
 )
@@ -20,6 +26,9 @@ Note that the user's password must be at least 8 characters long. eg.:
 			Usage(cmd)
 			return
 		}
+		if len(userPasswordFlag) < minPasswordLength {
+			Fatalf(cmd, "password must be at least %d characters long", minPasswordLength)
+		}
 		tc := NewTeresa()
 		user, err := tc.CreateUser(userNameFlag, userEmailFlag, userPasswordFlag, isAdminFlag)
 		if err != nil {
@@ -31,22 +40,170 @@ Note that the user's password must be at least 8 characters long. eg.:
 
 // delete user
 var deleteUserCmd = &cobra.Command{
-	Use:   "user",
+	Use:   "user [email]",
 	Short: "Delete an user",
 	Long: `Delete an user.
+
+The user to delete is shown and must be confirmed before deleting it:
+
+	$ teresa delete user john.doe@mydomain.com
+
+To skip the confirmation, eg. on scripts:
+
+	$ teresa delete user john.doe@mydomain.com --yes
 	`,
 	Run: func(cmd *cobra.Command, args []string) {
-		if userIDFlag == 0 {
+		if len(args) == 0 && userIDFlag == 0 {
 			Usage(cmd)
 			return
 		}
-		if err := NewTeresa().DeleteUser(userIDFlag); err != nil {
+		tc := NewTeresa()
+		id := userIDFlag
+		if len(args) > 0 {
+			u, err := tc.GetUserByEmail(args[0])
+			if err != nil {
+				log.Fatal(err)
+			}
+			if !yesFlag {
+				fmt.Printf("User %s <%s> (admin: %s) will be deleted.\n", *u.Name, *u.Email, yesNo(u.IsAdmin != nil && *u.IsAdmin))
+			}
+			id = u.ID
+		} else if !yesFlag {
+			fmt.Printf("User with ID %d will be deleted.\n", id)
+		}
+		if !yesFlag && !confirm("Are you sure?") {
+			log.Fatal("Aborted, nothing was deleted")
+		}
+		if err := tc.DeleteUser(id); err != nil {
 			Fatalf(cmd, "Failed to delete user, err: %s\n", err)
 		}
 		log.Infof("User deleted.")
 	},
 }
 
+var getUsersCmd = &cobra.Command{
+	Use:   "users",
+	Short: "Get users",
+	Long: `List all the users, with their teams and whether they are admins.
+
+eg.:
+
+	$ teresa get users
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		l, err := NewTeresa().GetUsers()
+		if err != nil {
+			log.Fatalf("Failed to retrieve users: %s", err)
+		}
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{"NAME", "EMAIL", "ADMIN", "TEAMS"})
+		table.SetAlignment(tablewriter.ALIGN_LEFT)
+		table.SetAutoWrapText(false)
+		for _, u := range l {
+			table.Append([]string{*u.Name, *u.Email, yesNo(u.IsAdmin != nil && *u.IsAdmin), strings.Join(teamNames(u.Teams), ", ")})
+		}
+		table.Render()
+	},
+}
+
+var getUserCmd = &cobra.Command{
+	Use:   "user email",
+	Short: "Get user details",
+	Long: `Return the details of an user.
+
+eg.:
+
+	$ teresa get user john.doe@mydomain.com
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) == 0 {
+			Usage(cmd)
+			return
+		}
+		tc := NewTeresa()
+		u, err := tc.GetUserByEmail(args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+		if u, err = tc.GetUserDetails(u.ID); err != nil {
+			log.Fatalf("Failed to retrieve the user: %s", err)
+		}
+		printUser(u)
+	},
+}
+
+var setUserCmd = &cobra.Command{
+	Use:   "user email",
+	Short: "Set user attributes",
+	Long: `Update the name of an user or grant and revoke admin rights.
+
+eg.:
+
+	$ teresa set user john.doe@mydomain.com --name "John Doe"
+	$ teresa set user john.doe@mydomain.com --admin
+	$ teresa set user john.doe@mydomain.com --no-admin
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) == 0 {
+			Usage(cmd)
+			return
+		}
+		nameChanged := cmd.Flags().Changed("name")
+		if err := validateSetUserFlags(nameChanged, isAdminFlag, noAdminFlag); err != nil {
+			Fatalf(cmd, "%s", err)
+		}
+		tc := NewTeresa()
+		u, err := tc.GetUserByEmail(args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+		if nameChanged {
+			u.Name = &userNameFlag
+		}
+		if isAdminFlag || noAdminFlag {
+			u.IsAdmin = &isAdminFlag
+		}
+		// teams membership is not changed through the user
+		u.Teams = nil
+		if u, err = tc.UpdateUser(u); err != nil {
+			log.Fatalf("Failed to update the user: %s", err)
+		}
+		log.Info("User updated.")
+		printUser(u)
+	},
+}
+
+func validateSetUserFlags(nameChanged, admin, noAdmin bool) error {
+	if !nameChanged && !admin && !noAdmin {
+		return newInputError("nothing to change, provide --name, --admin or --no-admin")
+	}
+	if admin && noAdmin {
+		return newInputError("--admin and --no-admin can't be used together")
+	}
+	return nil
+}
+
+func printUser(u *models.User) {
+	fmt.Printf("\nName: %s\n", *u.Name)
+	fmt.Printf("Email: %s\n", *u.Email)
+	fmt.Printf("Admin: %s\n", yesNo(u.IsAdmin != nil && *u.IsAdmin))
+	if len(u.Teams) > 0 {
+		fmt.Print("\nTeams:\n")
+		for _, n := range teamNames(u.Teams) {
+			fmt.Printf("  %s\n", n)
+		}
+	}
+	fmt.Println()
+}
+
+func teamNames(l []*models.Team) []string {
+	names := make([]string, len(l))
+	for i, t := range l {
+		names[i] = *t.Name
+	}
+	return names
+}
+
 func init() {
 	createCmd.AddCommand(userCmd)
 	userCmd.Flags().StringVar(&userNameFlag, "name", "", "user name [required]")
@@ -55,5 +212,14 @@ func init() {
 	userCmd.Flags().BoolVar(&isAdminFlag, "admin", false, "admin")
 
 	deleteCmd.AddCommand(deleteUserCmd)
-	deleteUserCmd.Flags().Int64Var(&userIDFlag, "id", 0, "user ID [required]")
+	deleteUserCmd.Flags().Int64Var(&userIDFlag, "id", 0, "user ID, instead of the email")
+	deleteUserCmd.Flags().BoolVarP(&yesFlag, "yes", "y", false, "don't ask for confirmation")
+
+	getCmd.AddCommand(getUsersCmd)
+	getCmd.AddCommand(getUserCmd)
+
+	setCmd.AddCommand(setUserCmd)
+	setUserCmd.Flags().StringVar(&userNameFlag, "name", "", "new user name")
+	setUserCmd.Flags().BoolVar(&isAdminFlag, "admin", false, "grant admin rights")
+	setUserCmd.Flags().BoolVar(&noAdminFlag, "no-admin", false, "revoke admin rights")
 }
@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/luizalabs/teresa-api/models"
+)
+
+func TestGetUsers(t *testing.T) {
+	api := &fakeAPI{lists: map[string][]string{"/v1/users": newTestItems("user", 2*pageSize+1)}}
+	tc, ts := newTestClient(t, api)
+	defer ts.Close()
+
+	l, err := tc.GetUsers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(l) != 2*pageSize+1 || len(api.requests) != 3 {
+		t.Errorf("expected %d users in 3 pages, got %d in %d requests", 2*pageSize+1, len(l), len(api.requests))
+	}
+
+	u, err := tc.GetUserByEmail("user100@luizalabs.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *u.Name != "user100" {
+		t.Errorf("expected the user from the last page, got %s", *u.Name)
+	}
+	if _, err = tc.GetUserByEmail("nobody@luizalabs.com"); err == nil || !isSysError(err) {
+		t.Errorf("expected a not found error, got %v", err)
+	}
+}
+
+func TestUpdateUser(t *testing.T) {
+	api := &fakeAPI{responses: map[string]string{
+		"PUT /v1/users/7": `{"id": 7, "name": "John Doe", "email": "john@luizalabs.com", "isAdmin": true}`,
+	}}
+	tc, ts := newTestClient(t, api)
+	defer ts.Close()
+
+	name, email, admin := "John Doe", "john@luizalabs.com", true
+	u, err := tc.UpdateUser(&models.User{ID: 7, Name: &name, Email: &email, IsAdmin: &admin})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *u.Name != "John Doe" || !*u.IsAdmin {
+		t.Errorf("expected the updated user, got %+v", u)
+	}
+	if len(api.requests) != 1 || !strings.Contains(api.requests[0], `"isAdmin":true`) {
+		t.Errorf("expected the user on the request body, got %v", api.requests)
+	}
+	if strings.Contains(api.requests[0], "password") {
+		t.Errorf("expected the password to be left out, got %v", api.requests)
+	}
+
+	api.requests = nil
+	short := "Jo"
+	if _, err = tc.UpdateUser(&models.User{ID: 7, Name: &short, Email: &email, IsAdmin: &admin}); err == nil {
+		t.Error("expected an error for a too short name")
+	}
+	if len(api.requests) != 0 {
+		t.Errorf("expected the invalid user not to be sent, got %v", api.requests)
+	}
+}
+
+func TestValidateSetUserFlags(t *testing.T) {
+	var tests = []struct {
+		nameChanged, admin, noAdmin bool
+		ok                          bool
+	}{
+		{false, false, false, false},
+		{true, false, false, true},
+		{false, true, false, true},
+		{false, false, true, true},
+		{true, true, true, false},
+	}
+	for _, tt := range tests {
+		if err := validateSetUserFlags(tt.nameChanged, tt.admin, tt.noAdmin); (err == nil) != tt.ok {
+			t.Errorf("expected ok=%v for name=%v admin=%v no-admin=%v, got %v", tt.ok, tt.nameChanged, tt.admin, tt.noAdmin, err)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/spf13/cobra"
 )
@@ -10,13 +11,55 @@ import (
 var versionCmd = &cobra.Command{
 	Use:   "version",
 	Short: "Shows the client and selected server version",
+	Long: `Show the version of the cli and of the server in use, with the api
+versions it supports, warning when they aren't compatible.
+
+The server version is cached for an hour, use --refresh to check it again.
+
+eg.:
+
+	$ teresa version
+	$ teresa version --cluster staging --refresh
+	$ teresa version --only-client
+	`,
 	Run: func(cmd *cobra.Command, args []string) {
 		fmt.Printf("Client version: %s\n", version)
+		if onlyClientFlag {
+			return
+		}
+		if _, err := getCurrentClusterName(); err != nil {
+			log.WithError(err).Debug("No cluster in use, skipping the server version")
+			return
+		}
+		tc := NewTeresa()
+		var (
+			v   *serverVersion
+			err error
+		)
+		if refreshFlag {
+			v, err = tc.refreshServerVersion()
+		} else {
+			v, err = tc.ServerVersion()
+		}
+		if err != nil {
+			log.Fatalf("Failed to get the server version: %s", err)
+		}
+		if v.Unknown {
+			fmt.Println("Server version: unknown (the server doesn't report its version)")
+			return
+		}
+		fmt.Printf("Server version: %s\n", v.Version)
+		if len(v.APIVersions) > 0 {
+			fmt.Printf("Server api versions: %s (using %s)\n", strings.Join(v.APIVersions, ", "), tc.apiVersion)
+		}
+		for _, w := range v.compatibilityWarnings(tc.apiVersion) {
+			log.Warn(w)
+		}
 	},
 }
 
 func init() {
 	RootCmd.AddCommand(versionCmd)
-	// TODO: will be necessary in the future when we will return more info
-	// versionCmd.Flags().Bool("only-client", false, "Show only the client info")
+	versionCmd.Flags().BoolVar(&onlyClientFlag, "only-client", false, "Show only the client info")
+	versionCmd.Flags().BoolVar(&refreshFlag, "refresh", false, "Ignore the cached server version")
 }
@@ -0,0 +1,64 @@
+#!/bin/sh
+# Signs the release binaries and writes the release index of the update
+# command to stdout. The binaries are named teresa-<os>-<arch>[.exe], like
+# the ones of make release, and are served next to the index:
+#
+#   scripts/sign-release.sh 0.2.0 release-key.pem dist/teresa-* > dist/index.json
+#
+# The key is an ed25519 private key in PEM, created once with:
+#
+#   openssl genpkey -algorithm ed25519 -out release-key.pem
+#
+# Its public key, built in the cli with UPDATE_PUBLIC_KEY, is printed with:
+#
+#   scripts/sign-release.sh -pubkey release-key.pem
+#
+# Requires openssl 1.1.1 or newer.
+set -e
+
+usage() {
+    echo "usage: $0 version key.pem binary..." >&2
+    echo "       $0 -pubkey key.pem" >&2
+    exit 2
+}
+
+# the raw 32 bytes key is the end of the DER encoding
+if [ "$1" = "-pubkey" ]; then
+    [ $# -eq 2 ] || usage
+    openssl pkey -in "$2" -pubout -outform DER | tail -c 32 | base64
+    exit 0
+fi
+
+[ $# -ge 3 ] || usage
+version=$1
+key=$2
+shift 2
+
+sha256() {
+    if command -v sha256sum >/dev/null; then
+        sha256sum "$1" | cut -d ' ' -f 1
+    else
+        shasum -a 256 "$1" | cut -d ' ' -f 1
+    fi
+}
+
+printf '{\n  "version": "%s",\n  "binaries": {' "$version"
+sep=""
+for bin in "$@"; do
+    name=$(basename "$bin")
+    # teresa-linux-amd64 or teresa-windows-amd64.exe to linux/amd64
+    platform=$(echo "${name%.exe}" | sed -n 's/^teresa-\([a-z0-9]*\)-\([a-z0-9]*\)$/\1\/\2/p')
+    if [ -z "$platform" ]; then
+        echo "$bin: expected a name like teresa-linux-amd64" >&2
+        exit 1
+    fi
+    sum=$(sha256 "$bin")
+    # the message checked by the cli, see updateSignedMessage
+    msg=$(mktemp)
+    printf 'teresa %s %s %s' "$version" "$platform" "$sum" > "$msg"
+    sig=$(openssl pkeyutl -sign -inkey "$key" -rawin -in "$msg" | base64 | tr -d '\n')
+    rm -f "$msg"
+    printf '%s\n    "%s": {"url": "%s", "sha256": "%s", "signature": "%s"}' "$sep" "$platform" "$name" "$sum" "$sig"
+    sep=","
+done
+printf '\n  }\n}\n'